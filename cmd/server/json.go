@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/goprerender/prerender/pkg/executor"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"time"
+)
+
+// pageJSON is the format=json response of /render.
+type pageJSON struct {
+	URL string `json:"url"`
+	renderer.Metadata
+	HTML  string `json:"html"`
+	Cache string `json:"cache"`
+	// RenderTime is in milliseconds and Age in seconds, both are 0 when the render is not known.
+	RenderTime int64      `json:"renderTime"`
+	RenderedAt *time.Time `json:"renderedAt,omitempty"`
+	Modified   *time.Time `json:"modified,omitempty"`
+	Age        int        `json:"age"`
+}
+
+// writeJSON sends the page with its metadata as JSON.
+func writeJSON(c *routing.Context, queryString string, res *executor.Result, status int) error {
+	html, err := res.Page.HTML()
+	if err != nil {
+		return err
+	}
+
+	v := pageJSON{
+		URL:        queryString,
+		HTML:       html,
+		Cache:      string(res.Cache),
+		RenderTime: res.RenderTime().Milliseconds(),
+		Age:        int(res.Age().Seconds()),
+	}
+	if m := res.Page.Metadata(); m != nil {
+		v.Metadata = *m
+	}
+	if renderedAt := res.RenderedAt(); !renderedAt.IsZero() {
+		v.RenderedAt = &renderedAt
+	}
+	if modified := res.Page.Modified(); !modified.IsZero() {
+		v.Modified = &modified
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.Response.Header().Set("Content-Type", "application/json")
+	c.Response.WriteHeader(status)
+	_, err = c.Response.Write(b)
+	return err
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -8,17 +9,24 @@ import (
 	"github.com/go-ozzo/ozzo-routing/v2/access"
 	"github.com/go-ozzo/ozzo-routing/v2/fault"
 	"github.com/go-ozzo/ozzo-routing/v2/slash"
+	"github.com/goprerender/prerender/internal/admin"
 	"github.com/goprerender/prerender/internal/cachers/rstorage"
+	"github.com/goprerender/prerender/internal/config"
 	"github.com/goprerender/prerender/internal/healthcheck"
+	"github.com/goprerender/prerender/internal/links"
+	"github.com/goprerender/prerender/internal/sitemap"
 	"github.com/goprerender/prerender/pkg/api/storage"
 	"github.com/goprerender/prerender/pkg/executor"
 	prLog "github.com/goprerender/prerender/pkg/log"
 	"github.com/goprerender/prerender/pkg/renderer"
 	"github.com/goprerender/prerender/pkg/url"
 	"google.golang.org/grpc"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -31,8 +39,12 @@ const (
 var Version = "1.0.0-beta.0"
 
 var flagDebug = flag.Bool("debug", false, "debug level")
+var flagConfig = flag.String("config", "config.json", "path to the config file")
+var flagMetrics = flag.String("metrics", "", "address serving the metrics at /debug/vars, e.g. :3001")
 
 func main() {
+	flag.Parse()
+
 	// create root logger tagged with server version
 	logger := prLog.New(*flagDebug).With(nil, "PR Server", Version)
 
@@ -47,16 +59,35 @@ func main() {
 
 	pc := rstorage.New(sc, logger)
 
-	r := renderer.NewRenderer(logger)
+	// load application configurations
+	cfg, err := config.Load(*flagConfig, logger)
+	if err != nil {
+		logger.Errorf("failed to load application configuration: %s", err)
+		os.Exit(-1)
+	}
+
+	r := renderer.NewRenderer(cfg.Renderer, logger)
 	defer r.Cancel()
 
-	e := executor.NewExecutor(r, pc, logger)
+	e := executor.NewExecutor(r, pc, cfg.Executor, logger)
+
+	if *flagMetrics != "" {
+		go func() {
+			if err := http.ListenAndServe(*flagMetrics, nil); err != nil {
+				logger.Error("Metrics server error: ", err)
+			}
+		}()
+	}
+
+	// the refreshes started by the worker run here, so their renders share the scheduler and
+	// rate limits of the crawler requests
+	runner := sitemap.NewRunner(e, cfg.Audit, links.NewChecker(cfg.Links, r), logger)
 
 	// build HTTP server
 	address := fmt.Sprintf(":%v", "3000")
 	hs := &http.Server{
 		Addr:    address,
-		Handler: buildHandler(e, logger),
+		Handler: buildHandler(e, runner, cfg.Server, logger),
 	}
 
 	// start the HTTP server with graceful shutdown
@@ -68,7 +99,7 @@ func main() {
 	}
 }
 
-func buildHandler(e *executor.Executor, logger prLog.Logger) *routing.Router {
+func buildHandler(e *executor.Executor, runner *sitemap.Runner, server config.ServerConfig, logger prLog.Logger) *routing.Router {
 	router := routing.New()
 
 	router.Use(
@@ -79,13 +110,15 @@ func buildHandler(e *executor.Executor, logger prLog.Logger) *routing.Router {
 	)
 
 	healthcheck.RegisterHandlers(router, Version)
+	admin.RegisterHandlers(router, e, runner, server.AdminToken, logger)
 
-	router.Get("/render", handleRequest(e, logger))
+	router.Get("/render", handleRequest(e, time.Duration(server.MaxAge), logger))
+	router.Post("/render", handleHTML(e))
 
 	return router
 }
 
-func handleRequest(e *executor.Executor, logger prLog.Logger) routing.Handler {
+func handleRequest(e *executor.Executor, maxAge time.Duration, logger prLog.Logger) routing.Handler {
 	return func(c *routing.Context) error {
 		c.Response.Header().Set("X-Prerender", "Prerender by (+https://github.com/goprerender/prerender)")
 
@@ -110,8 +143,52 @@ func handleRequest(e *executor.Executor, logger prLog.Logger) routing.Handler {
 			force = true
 		}
 
-		res, err := e.Execute(queryString, force)
+		const xDiagnostics = "x_diagnostics=true"
+
+		diagnostics := false
+
+		if strings.Contains(queryString, xDiagnostics) {
+			queryString = strings.Replace(queryString, "&"+xDiagnostics, "", -1)
+			queryString = strings.Replace(queryString, xDiagnostics, "", -1)
+			diagnostics = true
+		}
+
+		const format = "format=json"
+
+		asJSON := false
+
+		if strings.Contains(queryString, format) {
+			queryString = strings.Replace(queryString, "&"+format, "", -1)
+			queryString = strings.Replace(queryString, format, "", -1)
+			asJSON = true
+		}
+
+		acceptLanguage := c.Request.Header.Get("Accept-Language")
+
+		res, err := e.Execute(queryString, force, acceptLanguage, renderer.Interactive)
+		if diagnostics {
+			return writeDiagnostics(c, e, queryString, acceptLanguage)
+		}
+		// redirects and errors tell how they were served too
+		writeCacheHeaders(c, res)
 		if err != nil {
+			var re *renderer.RedirectError
+			if errors.As(err, &re) {
+				http.Redirect(c.Response, c.Request, re.Location, re.Status)
+				c.Abort()
+				return nil
+			}
+			var ve *renderer.ValidationError
+			if errors.As(err, &ve) || err == executor.ErrRecentlyFailed {
+				// the failed render is not tried again before the failure TTL
+				if retryAfter := int(e.RetryAfter().Seconds()); retryAfter > 0 {
+					c.Response.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				}
+				return c.WriteWithStatus(err.Error(), http.StatusServiceUnavailable)
+			}
+			if err == executor.ErrCircuitOpen || err == renderer.ErrRateLimited {
+				return c.WriteWithStatus(err.Error(), http.StatusServiceUnavailable)
+			}
 			if err == url.ErrRedirect {
 				status := http.StatusMovedPermanently
 				if c.Request.Method != "GET" {
@@ -123,12 +200,153 @@ func handleRequest(e *executor.Executor, logger prLog.Logger) routing.Handler {
 			return err
 		}
 
-		res = stripAllTags(res, "<script>", "</script>")
+		// the page differs by locale only on the hosts picking one of several locales
+		if len(e.Locales(queryString)) > 1 {
+			c.Response.Header().Add("Vary", "Accept-Language")
+		}
+
+		page := res.Page
+
+		status := http.StatusOK
+		if page.NoIndex() {
+			status = http.StatusNotFound
+		}
+
+		if asJSON {
+			return writeJSON(c, queryString, res, status)
+		}
+
+		header := c.Response.Header()
+		header.Set("ETag", page.ETag())
+		if modified := page.Modified(); !modified.IsZero() {
+			header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		}
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+
+		if status == http.StatusOK && !force && notModified(c.Request, page.ETag(), page.Modified()) {
+			header.Add("Vary", "Accept-Encoding")
+			c.Response.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+
+		return writePage(c, page, status)
+	}
+}
 
-		if strings.Contains(res, "<meta name=\"robots\" content=\"noindex\">") {
-			return c.WriteWithStatus(res, http.StatusNotFound)
+// writeCacheHeaders tells how the page was served: from the cache or rendered, how long the render
+// took, how old the page is and which Chrome rendered it.
+func writeCacheHeaders(c *routing.Context, res *executor.Result) {
+	header := c.Response.Header()
+	header.Set("X-Prerender-Cache", string(res.Cache))
+	if res.RenderedAt().IsZero() {
+		return
+	}
+	header.Set("X-Prerender-Render-Time", strconv.FormatInt(res.RenderTime().Milliseconds(), 10))
+	header.Set("X-Prerender-Age", strconv.Itoa(int(res.Age().Seconds())))
+	if endpoint := res.Endpoint(); endpoint != "" {
+		header.Set("X-Prerender-Endpoint", endpoint)
+	}
+}
+
+// writePage sends the page, compressed as it is cached to the clients accepting its encoding.
+func writePage(c *routing.Context, page *executor.Page, status int) error {
+	header := c.Response.Header()
+	header.Add("Vary", "Accept-Encoding")
+	header.Set("Content-Type", "text/html; charset=utf-8")
+
+	body := page.Compressed()
+	if accepts(c.Request, page.Encoding()) {
+		header.Set("Content-Encoding", page.Encoding())
+	} else {
+		html, err := page.HTML()
+		if err != nil {
+			return err
 		}
+		body = []byte(html)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
 
+	c.Response.WriteHeader(status)
+	_, err := c.Response.Write(body)
+	return err
+}
+
+// accepts reports whether the Accept-Encoding header of the request allows encoding.
+func accepts(r *http.Request, encoding string) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(accepted, ";")
+		name := strings.TrimSpace(parts[0])
+		if name != encoding && name != "*" {
+			continue
+		}
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); strings.HasPrefix(param, "q=") && err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// writeDiagnostics responds with the diagnostics of the last render of the query as JSON.
+func writeDiagnostics(c *routing.Context, e *executor.Executor, queryString, acceptLanguage string) error {
+	d, err := e.Diagnostics(queryString, acceptLanguage)
+	if err != nil {
+		return c.WriteWithStatus("error: no diagnostics for url", http.StatusNotFound)
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	c.Response.Header().Set("Content-Type", "application/json")
+	return c.Write(b)
+}
+
+const maxHTMLSize = 5 << 20
+
+// handleHTML renders the HTML document posted in the body. The url param is its optional base URL,
+// screenshot=true returns a PNG instead of the HTML.
+func handleHTML(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		c.Response.Header().Set("X-Prerender", "Prerender by (+https://github.com/goprerender/prerender)")
+		c.Response.Header().Set("X-Prerender-Cache", string(executor.CacheBypass))
+
+		// one byte over the limit tells a too large body from the other read errors
+		body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxHTMLSize+1))
+		if err != nil {
+			return c.WriteWithStatus("error: can't read body", http.StatusBadRequest)
+		}
+		if len(body) > maxHTMLSize {
+			return c.WriteWithStatus("error: html body too large", http.StatusRequestEntityTooLarge)
+		}
+		if len(body) == 0 {
+			return c.WriteWithStatus("error: html body not found in request", http.StatusBadRequest)
+		}
+
+		screenshot := c.Query("screenshot") == "true"
+
+		start := time.Now()
+		res, err := e.RenderHTML(string(body), c.Query("url"), screenshot)
+
+		header := c.Response.Header()
+		header.Set("X-Prerender-Render-Time", strconv.FormatInt(time.Since(start).Milliseconds(), 10))
+		header.Set("X-Prerender-Endpoint", e.Endpoint())
+		if err == renderer.ErrRateLimited {
+			return c.WriteWithStatus(err.Error(), http.StatusServiceUnavailable)
+		}
+		if err != nil {
+			return err
+		}
+
+		if screenshot {
+			c.Response.Header().Set("Content-Type", "image/png")
+		} else {
+			c.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
 		return c.Write(res)
 	}
 }
@@ -172,21 +390,3 @@ func getStringInBetween(str string, start string, end string) (result string) {
 	}
 	return str[s:e]
 }
-
-func stripAllTags(str, start, end string) string {
-
-	s := strings.Index(str, start[:len(start)-1])
-	if s == -1 {
-		return str
-	}
-
-	e := strings.Index(str, end)
-	if e == -1 {
-		return str
-	}
-	e += len(end)
-
-	str = str[0:s] + str[e:]
-
-	return stripAllTags(str, start, end)
-}
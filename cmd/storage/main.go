@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"github.com/bluele/gcache"
@@ -12,6 +13,8 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -21,20 +24,44 @@ const (
 	duration = time.Hour * 24 * 7
 )
 
-var gc = gcache.New(100000).
-	LRU().
-	Build()
+// sidePerPage is the default number of side entries per page: the list and the slots of its
+// versions, its metadata, SEO metadata and diagnostics.
+const sidePerPage = 10
 
-// server is used to implement Saver.
+// mutex makes Swap atomic against the other writes
+var mutex sync.Mutex
+
+// server is used to implement Saver. Pages and their side data ("<key>:versions", "<key>:v0",
+// "<key>:metadata"...) are kept in separate LRU caches, so side data never evicts pages.
 type server struct {
 	storage.UnimplementedStorageServer
+	pages  gcache.Cache
+	side   gcache.Cache
 	logger log.Logger
 }
 
+func newServer(pages, side int, logger log.Logger) *server {
+	return &server{
+		pages:  gcache.New(pages).LRU().Build(),
+		side:   gcache.New(side).LRU().Build(),
+		logger: logger,
+	}
+}
+
+// cache returns the cache holding key.
+func (s *server) cache(key string) gcache.Cache {
+	if strings.Contains(key, ":") {
+		return s.side
+	}
+	return s.pages
+}
+
 // Store implements Saver
 func (s *server) Store(ctx context.Context, in *storage.StoreRequest) (*storage.StoreReply, error) {
 	//s.logger.Infof("Received: %v, %v", in.Page.GetData(), in.Page.GetHash())
-	err := gc.SetWithExpire(in.Page.GetHash(), in.Page.GetData(), duration)
+	mutex.Lock()
+	defer mutex.Unlock()
+	err := s.cache(in.Page.GetHash()).SetWithExpire(in.Page.GetHash(), in.Page.GetData(), duration)
 	if err != nil {
 		return nil, status.Error(codes.Unknown, "")
 	}
@@ -43,7 +70,7 @@ func (s *server) Store(ctx context.Context, in *storage.StoreRequest) (*storage.
 
 func (s *server) Get(ctx context.Context, in *storage.GetRequest) (*storage.GetReplay, error) {
 	//s.logger.Infof("Received: %v", in.GetHash())
-	value, err := gc.Get(in.Hash)
+	value, err := s.cache(in.Hash).Get(in.Hash)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, "not found")
 	}
@@ -52,17 +79,38 @@ func (s *server) Get(ctx context.Context, in *storage.GetRequest) (*storage.GetR
 	}, nil
 }
 
+// Len returns the number of pages, without their side data.
 func (s *server) Len(ctx context.Context, in *storage.LenRequest) (*storage.LenReplay, error) {
 	//s.logger.Warn("Received: Len request")
-	return &storage.LenReplay{Length: int32(gc.Len(true))}, nil
+	return &storage.LenReplay{Length: int32(s.pages.Len(true))}, nil
+}
+
+// Swap stores the page only if its current data is the expected one
+func (s *server) Swap(ctx context.Context, in *storage.SwapRequest) (*storage.SwapReply, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	c := s.cache(in.Page.GetHash())
+	value, err := c.Get(in.Page.GetHash())
+	exists := err == nil
+	if exists != in.GetExists() || exists && !bytes.Equal(value.([]byte), in.GetOld()) {
+		return &storage.SwapReply{Swapped: false}, nil
+	}
+	if err := c.SetWithExpire(in.Page.GetHash(), in.Page.GetData(), duration); err != nil {
+		return nil, status.Error(codes.Unknown, "")
+	}
+	return &storage.SwapReply{Swapped: true}, nil
 }
 
 // Version indicates the current version of the application.
 var Version = "1.0.0-beta.0"
 
 var flagDebug = flag.Bool("debug", false, "debug level")
+var flagPages = flag.Int("pages", 100000, "number of pages kept")
+var flagSide = flag.Int("side", 0, "number of side entries kept (versions, metadata...), 10 per page by default")
 
 func main() {
+	flag.Parse()
 
 	// create root logger tagged with server version
 	logger := log.New(*flagDebug).With(nil, "PR Storage", Version)
@@ -73,7 +121,12 @@ func main() {
 		logger.Errorf("failed to listen: %v", err)
 	}
 	s := grpc.NewServer()
-	storage.RegisterStorageServer(s, &server{logger: logger})
+	side := *flagSide
+	if side <= 0 {
+		side = *flagPages * sidePerPage
+	}
+	storage.RegisterStorageServer(s, newServer(*flagPages, side, logger))
+	logger.Infof("keeping %d pages and %d side entries", *flagPages, side)
 
 	// graceful shutdown
 	c := make(chan os.Signal, 1)
@@ -2,7 +2,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/goprerender/prerender/internal/cachers/rstorage"
+	"github.com/goprerender/prerender/internal/config"
+	"github.com/goprerender/prerender/internal/links"
 	"github.com/goprerender/prerender/internal/sitemap"
 	"github.com/goprerender/prerender/pkg/api/storage"
 	"github.com/goprerender/prerender/pkg/executor"
@@ -10,9 +13,12 @@ import (
 	"github.com/goprerender/prerender/pkg/renderer"
 	"github.com/robfig/cron/v3"
 	"google.golang.org/grpc"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -25,59 +31,117 @@ const (
 var Version = "1.0.0-beta.0"
 
 var flagDebug = flag.Bool("debug", false, "debug level")
+var flagConfig = flag.String("config", "config.json", "path to the config file")
+var flagServer = flag.String("server", "http://localhost:3000", "prerender server running the sitemap refresh, when server.adminToken is set")
 var flagForce = flag.Bool("force", false, "force refresh")
+var flagMetrics = flag.String("metrics", "", "address serving the metrics at /debug/vars, e.g. :3001")
 
+// The worker refreshes the sitemaps when it starts and on the sitemap.schedule cron spec. With
+// server.adminToken set the server runs the refreshes, so their renders share the render scheduler
+// and host rate limits of the crawler requests. Without it the worker renders them itself.
 func main() {
+	flag.Parse()
+
 	// create root logger tagged with server version
 	logger := prLog.New(*flagDebug).With(nil, "PR Worker", Version)
 
-	// Set up a connection to the server.
-	conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithBlock())
+	// load application configurations
+	cfg, err := config.Load(*flagConfig, logger)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+		logger.Errorf("failed to load application configuration: %s", err)
+		os.Exit(-1)
 	}
-	defer conn.Close()
-
-	sc := storage.NewStorageClient(conn)
-
-	pc := rstorage.New(sc, logger)
 
-	r := renderer.NewRenderer(logger)
-	defer r.Cancel()
-
-	e := executor.NewExecutor(r, pc, logger)
+	var refresh func(force bool)
+	if cfg.Server.AdminToken != "" {
+		refresh = trigger(*flagServer, cfg.Server.AdminToken, logger)
+	} else {
+		logger.Warn("server.adminToken not set, the sitemaps are rendered by the worker, not the server")
+		refresh = standalone(cfg, logger)
+	}
 
 	pl := cron.VerbosePrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))
-
 	c := cron.New(cron.WithChain(
 		cron.SkipIfStillRunning(pl)))
 
-	startCroneRefresh(e, c, logger)
-
-	var sm = func() {
-		sitemap.BySitemap(e, *flagForce, logger)
-		c.Start()
+	if spec := cfg.Sitemap.Schedule; spec != "" {
+		if _, err := c.AddFunc(spec, func() { refresh(true) }); err != nil {
+			logger.Errorf("bad sitemap schedule %q: %s", spec, err)
+			os.Exit(-1)
+		}
+		logger.Infof("Sitemap refresh scheduled at %q", spec)
 	}
 
-	go sm()
+	go func() {
+		if cfg.Sitemap.OnStart {
+			refresh(*flagForce)
+		}
+		c.Start()
+	}()
 
 	exit := make(chan os.Signal, 1)
 	signal.Notify(exit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	<-exit
 	logger.Info("Service the server received a stop signal...")
+	c.Stop()
 	time.Sleep(15 * time.Second)
 }
 
-func startCroneRefresh(e *executor.Executor, c *cron.Cron, logger prLog.Logger) {
-	spec := "01 00 * * *"
-	//spec := "*/1 * * * *"
-	_, err := c.AddFunc(spec, func() {
-		logger.Debug(spec)
-		sitemap.BySitemap(e, true, logger)
-	})
+// trigger returns a refresh starting the sitemap refresh of the server.
+func trigger(server, token string, logger prLog.Logger) func(force bool) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	return func(force bool) {
+		refreshURL := fmt.Sprintf("%s/admin/sitemap?force=%t", strings.TrimRight(server, "/"), force)
+		req, err := http.NewRequest(http.MethodPost, refreshURL, nil)
+		if err != nil {
+			logger.Errorf("failed to start the sitemap refresh: %s", err)
+			return
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			logger.Errorf("failed to start the sitemap refresh: %s", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusAccepted {
+			logger.Errorf("failed to start the sitemap refresh: %s %s", resp.Status, body)
+			return
+		}
+		logger.Info("Sitemap refresh started by the server")
+	}
+}
+
+// standalone returns a refresh rendering the sitemaps in the worker process.
+func standalone(cfg *config.Config, logger prLog.Logger) func(force bool) {
+	// Set up a connection to the server.
+	conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithBlock())
 	if err != nil {
-		panic(err)
+		log.Fatalf("did not connect: %v", err)
+	}
+
+	sc := storage.NewStorageClient(conn)
+
+	pc := rstorage.New(sc, logger)
+
+	r := renderer.NewRenderer(cfg.Renderer, logger)
+
+	e := executor.NewExecutor(r, pc, cfg.Executor, logger)
+
+	if *flagMetrics != "" {
+		go func() {
+			if err := http.ListenAndServe(*flagMetrics, nil); err != nil {
+				logger.Error("Metrics server error: ", err)
+			}
+		}()
+	}
+
+	runner := sitemap.NewRunner(e, cfg.Audit, links.NewChecker(cfg.Links, r), logger)
+	return func(force bool) {
+		runner.Run(force)
 	}
-	logger.Info("Crone Refresh init Done")
 }
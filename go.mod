@@ -3,23 +3,24 @@ module github.com/goprerender/prerender
 go 1.15
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/bluele/gcache v0.0.2
 	github.com/chromedp/cdproto v0.0.0-20220124012806-175728ec2004
 	github.com/chromedp/chromedp v0.7.6
 	github.com/go-ozzo/ozzo-routing/v2 v2.4.0
 	github.com/golang/protobuf v1.5.2
 	github.com/google/uuid v1.3.0
+	github.com/klauspost/compress v1.15.0
 	github.com/kr/pretty v0.3.0 // indirect
 	github.com/mattn/go-colorable v0.1.12
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/rogpeppe/go-internal v1.8.1 // indirect
 	github.com/stretchr/testify v1.7.0
-	github.com/yterajima/go-sitemap v0.3.0
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.7.0 // indirect
 	go.uber.org/zap v1.20.0
 	golang.org/x/lint v0.0.0-20191125180803-fdd1cda4f05f // indirect
-	golang.org/x/net v0.0.0-20220121210141-e204ce36a2ba // indirect
+	golang.org/x/net v0.0.0-20220121210141-e204ce36a2ba
 	golang.org/x/sys v0.0.0-20220128215802-99c3d69c2c27 // indirect
 	google.golang.org/genproto v0.0.0-20220118154757-00ab72f36ad5 // indirect
 	google.golang.org/grpc v1.44.0
@@ -0,0 +1,208 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/goprerender/prerender/internal/sitemap"
+	"github.com/goprerender/prerender/pkg/executor"
+	"github.com/goprerender/prerender/pkg/log"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RegisterHandlers registers the handlers of the admin endpoints. They answer only the requests
+// with the bearer token, and are disabled when token is empty.
+func RegisterHandlers(r *routing.Router, e *executor.Executor, runner *sitemap.Runner, token string, logger log.Logger) {
+	rg := r.Group("/admin", authorize(token))
+
+	rg.Get("/har", getHAR(e))
+	rg.Post("/har", recordHAR(e, logger))
+	rg.Get("/queue", getQueue(e))
+	rg.Post("/recache", recache(e))
+	rg.Get("/versions", getVersions(e))
+	rg.Get(`/versions/<id:\d+>`, getVersion(e))
+	rg.Get(`/diff/<from:\d+>/<to:\d+>`, getDiff(e))
+	rg.Post(`/restore/<id:\d+>`, restore(e))
+	rg.Get("/seo", getSEO(e))
+	rg.Post("/sitemap", refreshSitemaps(runner))
+}
+
+// authorize aborts the requests without the bearer token.
+func authorize(token string) routing.Handler {
+	return func(c *routing.Context) error {
+		if token == "" {
+			c.Abort()
+			return c.WriteWithStatus("error: admin endpoints disabled, server.adminToken not set", http.StatusForbidden)
+		}
+		given := strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Abort()
+			c.Response.Header().Set("WWW-Authenticate", "Bearer")
+			return c.WriteWithStatus("error: admin token required", http.StatusUnauthorized)
+		}
+		return nil
+	}
+}
+
+// refreshSitemaps starts a refresh of the sitemaps, force=true renders the cached pages too.
+func refreshSitemaps(runner *sitemap.Runner) routing.Handler {
+	return func(c *routing.Context) error {
+		if !runner.Start(c.Query("force") == "true") {
+			return c.WriteWithStatus("error: sitemap refresh already running", http.StatusConflict)
+		}
+		return c.WriteWithStatus("sitemap refresh started", http.StatusAccepted)
+	}
+}
+
+// getSEO returns the SEO metadata of the cached page of the url param.
+func getSEO(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		m, err := e.SEO(urlParam(c), c.Request.Header.Get("Accept-Language"))
+		if err != nil {
+			return c.WriteWithStatus("error: no SEO metadata for url", http.StatusNotFound)
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		c.Response.Header().Set("Content-Type", "application/json")
+		return c.Write(b)
+	}
+}
+
+// getVersions lists the kept versions of the page of the url param.
+func getVersions(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		versions, err := e.Versions(urlParam(c), c.Request.Header.Get("Accept-Language"))
+		if err != nil {
+			return c.WriteWithStatus("error: no versions for url", http.StatusNotFound)
+		}
+		b, err := json.Marshal(versions)
+		if err != nil {
+			return err
+		}
+		c.Response.Header().Set("Content-Type", "application/json")
+		return c.Write(b)
+	}
+}
+
+// getVersion returns the HTML of a version of the page.
+func getVersion(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		id, _ := strconv.Atoi(c.Param("id"))
+		res, err := e.Version(urlParam(c), c.Request.Header.Get("Accept-Language"), id)
+		if err != nil {
+			return c.WriteWithStatus("error: version not found", http.StatusNotFound)
+		}
+		c.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return c.Write(res)
+	}
+}
+
+// getDiff returns the line diff between two versions of the page.
+func getDiff(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		from, _ := strconv.Atoi(c.Param("from"))
+		to, _ := strconv.Atoi(c.Param("to"))
+		res, err := e.Diff(urlParam(c), c.Request.Header.Get("Accept-Language"), from, to)
+		if err != nil {
+			return c.WriteWithStatus("error: version not found", http.StatusNotFound)
+		}
+		c.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return c.Write(res)
+	}
+}
+
+// restore caches a previous version of the page again.
+func restore(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		id, _ := strconv.Atoi(c.Param("id"))
+		if err := e.Restore(urlParam(c), c.Request.Header.Get("Accept-Language"), id); err != nil {
+			return c.WriteWithStatus("error: version not restored, "+err.Error(), http.StatusNotFound)
+		}
+		return c.Write("ok")
+	}
+}
+
+// getQueue returns the render queue stats per traffic class.
+func getQueue(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		b, err := json.Marshal(e.QueueStats())
+		if err != nil {
+			return err
+		}
+		c.Response.Header().Set("Content-Type", "application/json")
+		return c.Write(b)
+	}
+}
+
+// recache renders the url param again for the webhooks of the sites, every locale variant is refreshed.
+func recache(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		query := urlParam(c)
+
+		locales := e.Locales(query)
+		if len(locales) == 0 {
+			locales = []string{""}
+		}
+		for _, locale := range locales {
+			_, err := e.Execute(query, true, locale, renderer.Recache)
+			var re *renderer.RedirectError
+			if err != nil && !errors.As(err, &re) {
+				return err
+			}
+		}
+		return c.Write("ok")
+	}
+}
+
+// getHAR downloads the last HAR recorded for the url param.
+func getHAR(e *executor.Executor) routing.Handler {
+	return func(c *routing.Context) error {
+		query := urlParam(c)
+		b, err := e.HAR(query, c.Request.Header.Get("Accept-Language"))
+		if err != nil {
+			return c.WriteWithStatus("error: no HAR recorded for url", http.StatusNotFound)
+		}
+		return writeHAR(c, b)
+	}
+}
+
+// recordHAR renders the url param again while recording a HAR and downloads it.
+func recordHAR(e *executor.Executor, logger log.Logger) routing.Handler {
+	return func(c *routing.Context) error {
+		query := urlParam(c)
+		har, err := e.RecordHAR(query, c.Request.Header.Get("Accept-Language"))
+		if har == nil {
+			if err == nil {
+				err = fmt.Errorf("error: HAR not recorded for %s", query)
+			}
+			return err
+		}
+		if err != nil {
+			logger.Warn("HAR recorded with render error: ", err)
+		}
+
+		b, err := json.Marshal(har)
+		if err != nil {
+			return err
+		}
+		return writeHAR(c, b)
+	}
+}
+
+func writeHAR(c *routing.Context, b []byte) error {
+	c.Response.Header().Set("Content-Type", "application/json")
+	c.Response.Header().Set("Content-Disposition", `attachment; filename="render.har"`)
+	return c.Write(b)
+}
+
+// urlParam returns the raw url param, the same way /render reads it.
+func urlParam(c *routing.Context) string {
+	return strings.TrimPrefix(c.Request.URL.RawQuery, "url=")
+}
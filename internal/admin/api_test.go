@@ -0,0 +1,31 @@
+package admin
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_authorize(t *testing.T) {
+	serve := func(token, header string) int {
+		r := routing.New()
+		r.Group("/admin", authorize(token)).Post("/recache", func(c *routing.Context) error {
+			return c.Write("ok")
+		})
+		req := httptest.NewRequest(http.MethodPost, "/admin/recache", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("", ""))
+	assert.Equal(t, http.StatusForbidden, serve("", "Bearer "))
+	assert.Equal(t, http.StatusUnauthorized, serve("secret", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve("secret", "Bearer other"))
+	assert.Equal(t, http.StatusOK, serve("secret", "Bearer secret"))
+}
@@ -0,0 +1,174 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"io/ioutil"
+	"sync"
+)
+
+var ErrUnknownCodec = errors.New("error: unknown codec")
+
+// Codec compresses the stored snapshots.
+type Codec interface {
+	// Name is also the HTTP content coding of the compressed data.
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var registry = struct {
+	sync.RWMutex
+	byID   map[byte]Codec
+	byName map[string]byte
+}{
+	byID:   map[byte]Codec{},
+	byName: map[string]byte{},
+}
+
+// Register adds a codec under an ID written in the snapshot header, IDs must never be reused.
+func Register(id byte, c Codec) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.byID[id]; ok {
+		panic(fmt.Sprintf("archive: codec %d registered twice", id))
+	}
+	registry.byID[id] = c
+	registry.byName[c.Name()] = id
+}
+
+// Lookup returns the codec registered with name.
+func Lookup(name string) (Codec, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	id, ok := registry.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, name)
+	}
+	return registry.byID[id], nil
+}
+
+func codecByID(id byte) (Codec, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	c, ok := registry.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownCodec, id)
+	}
+	return c, nil
+}
+
+func codecID(c Codec) (byte, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	id, ok := registry.byName[c.Name()]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCodec, c.Name())
+	}
+	return id, nil
+}
+
+const (
+	gzipID   = 1
+	brotliID = 2
+	zstdID   = 3
+)
+
+func init() {
+	Register(gzipID, Gzip)
+	Register(brotliID, Brotli)
+	Register(zstdID, Zstd)
+}
+
+var (
+	Gzip   Codec = gzipCodec{}
+	Brotli Codec = brotliCodec{}
+	Zstd   Codec = newZstdCodec()
+)
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
+
+type brotliCodec struct{}
+
+func (brotliCodec) Name() string {
+	return "br"
+}
+
+func (brotliCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	bw := brotli.NewWriterLevel(&buf, 6)
+	if _, err := bw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (brotliCodec) Decompress(data []byte) ([]byte, error) {
+	return ioutil.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+}
+
+// zstdCodec shares an encoder and a decoder, EncodeAll and DecodeAll are safe for concurrent use.
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCodec() zstdCodec {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		panic(err)
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		panic(err)
+	}
+	return zstdCodec{encoder: encoder, decoder: decoder}
+}
+
+func (zstdCodec) Name() string {
+	return "zstd"
+}
+
+func (c zstdCodec) Compress(data []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+func (c zstdCodec) Decompress(data []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(data, nil)
+}
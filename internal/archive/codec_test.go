@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestPack(t *testing.T) {
+	content := []byte("<html><body>Hello</body></html>")
+
+	for _, codec := range []Codec{Gzip, Brotli, Zstd} {
+		start := time.Now().Truncate(time.Millisecond)
+		b, err := Pack(codec, content, "example.com/", "page")
+		assert.NoError(t, err)
+
+		s, err := Unpack(b)
+		assert.NoError(t, err)
+		assert.Equal(t, codec.Name(), s.Codec.Name())
+		assert.Equal(t, "example.com/", s.Name)
+		assert.Equal(t, "page", s.Comment)
+		assert.False(t, s.Created.Before(start))
+		assert.False(t, s.Created.After(time.Now()))
+
+		res, err := s.Content()
+		assert.NoError(t, err)
+		assert.Equal(t, content, res)
+	}
+}
+
+func TestUnpack_gzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = "example.com/"
+	zw.Comment = "redirect 301"
+	zw.ModTime = time.Unix(1600000000, 0)
+	zw.Write([]byte("https://example.com/new"))
+	zw.Close()
+
+	s, err := Unpack(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "gzip", s.Codec.Name())
+	assert.Equal(t, "redirect 301", s.Comment)
+	assert.Equal(t, int64(1600000000), s.Created.Unix())
+
+	res, err := Read(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", string(res))
+}
+
+func TestUnpack_corrupt(t *testing.T) {
+	_, err := Unpack([]byte("<html>"))
+	assert.Equal(t, ErrCorrupt, err)
+
+	_, err = Unpack(append(magic, 99))
+	assert.ErrorIs(t, err, ErrUnknownCodec)
+
+	_, err = Unpack(append(magic, gzipID, 10, 'a'))
+	assert.Equal(t, ErrCorrupt, err)
+
+	b, _ := Pack(Zstd, []byte("<html>"), "", "")
+	_, err = Read(b[:len(b)-3])
+	assert.Error(t, err)
+}
+
+// BenchmarkCodecs compares the codecs on a rendered page, run with -benchmem. testdata/page.html
+// is the Go 1 release notes as served by golang.org, captured in golang.org/x/net/html/testdata.
+func BenchmarkCodecs(b *testing.B) {
+	page, err := ioutil.ReadFile("testdata/page.html")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, codec := range []Codec{Gzip, Brotli, Zstd} {
+		packed, err := Pack(codec, page, "", "")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(codec.Name()+"/compress", func(b *testing.B) {
+			b.SetBytes(int64(len(page)))
+			for i := 0; i < b.N; i++ {
+				if _, err := Pack(codec, page, "", ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(page))/float64(len(packed)), "ratio")
+		})
+
+		b.Run(codec.Name()+"/decompress", func(b *testing.B) {
+			b.SetBytes(int64(len(page)))
+			for i := 0; i < b.N; i++ {
+				if _, err := Read(packed); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
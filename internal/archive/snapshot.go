@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var ErrCorrupt = errors.New("error: corrupt snapshot")
+
+// magic starts the snapshots with a header, it can't start gzip data nor HTML.
+var magic = []byte("\x00PRZ")
+
+// Snapshot is stored data with its codec and metadata. Its binary form is
+//
+//	magic | codec ID | uvarint len | name | uvarint len | comment | uvarint unix ms | compressed data
+//
+// Data stored as plain gzip before the header existed is read as gzip snapshots,
+// with the name, comment and modification time of the gzip header.
+type Snapshot struct {
+	Codec Codec
+	// Name is what the data is, e.g. the host and path of a page.
+	Name    string
+	Comment string
+	// Created is when the snapshot was packed, zero if it is unknown.
+	Created time.Time
+	// Data is compressed with Codec.
+	Data []byte
+}
+
+// Pack compresses content with codec and returns the snapshot with its header.
+func Pack(codec Codec, content []byte, name, comment string) ([]byte, error) {
+	id, err := codecID(codec)
+	if err != nil {
+		return nil, err
+	}
+	data, err := codec.Compress(content)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(magic)+len(name)+len(comment)+len(data)+21))
+	buf.Write(magic)
+	buf.WriteByte(id)
+	writeString(buf, name)
+	writeString(buf, comment)
+	writeUvarint(buf, uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// Unpack reads the header of a snapshot, the data is not decompressed.
+func Unpack(b []byte) (*Snapshot, error) {
+	if !bytes.HasPrefix(b, magic) {
+		return unpackGzip(b)
+	}
+
+	r := bytes.NewReader(b[len(magic):])
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	codec, err := codecByID(id)
+	if err != nil {
+		return nil, err
+	}
+	name, err := readString(r)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := readString(r)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return &Snapshot{
+		Codec:   codec,
+		Name:    name,
+		Comment: comment,
+		Created: time.Unix(0, int64(ms)*int64(time.Millisecond)),
+		Data:    b[len(b)-r.Len():],
+	}, nil
+}
+
+// Content returns the decompressed data.
+func (s *Snapshot) Content() ([]byte, error) {
+	return s.Codec.Decompress(s.Data)
+}
+
+// Read unpacks a snapshot and returns its decompressed content.
+func Read(b []byte) ([]byte, error) {
+	s, err := Unpack(b)
+	if err != nil {
+		return nil, err
+	}
+	return s.Content()
+}
+
+func unpackGzip(b []byte) (*Snapshot, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	defer zr.Close()
+
+	return &Snapshot{
+		Codec:   Gzip,
+		Name:    zr.Name,
+		Comment: zr.Comment,
+		Created: zr.ModTime,
+		Data:    b,
+	}, nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var n [binary.MaxVarintLen64]byte
+	buf.Write(n[:binary.PutUvarint(n[:], v)])
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return "", ErrCorrupt
+	}
+	b := make([]byte, n)
+	if _, err := r.Read(b); err != nil && n > 0 {
+		return "", ErrCorrupt
+	}
+	return string(b), nil
+}
@@ -0,0 +1,246 @@
+// Package audit checks the SEO of the pages rendered by the sitemap worker and reports the issues.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/goprerender/prerender/internal/links"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config holds the audit settings.
+type Config struct {
+	// Dir is where the reports are written, "reports" by default.
+	Dir string `json:"dir"`
+	// SlowRender flags the pages rendered slower, 5s by default.
+	SlowRender renderer.Duration `json:"slowRender"`
+}
+
+func (c Config) dir() string {
+	if c.Dir == "" {
+		return "reports"
+	}
+	return c.Dir
+}
+
+func (c Config) slowRender() time.Duration {
+	if c.SlowRender <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.SlowRender)
+}
+
+// Kind is the kind of an issue.
+type Kind string
+
+// The kinds of issues.
+const (
+	MissingTitle         Kind = "missing title"
+	DuplicateTitle       Kind = "duplicate title"
+	MissingDescription   Kind = "missing description"
+	DuplicateDescription Kind = "duplicate description"
+	CanonicalMismatch    Kind = "canonical mismatch"
+	NoIndex              Kind = "noindex in sitemap"
+	EmptyBody            Kind = "empty body"
+	SlowRender           Kind = "slow render"
+	Redirect             Kind = "redirect"
+	RenderError          Kind = "render error"
+	BrokenLink           Kind = "broken link"
+	RedirectedLink       Kind = "redirected link"
+)
+
+// Page is the audited part of a rendered sitemap page.
+type Page struct {
+	URL         string
+	Locale      string
+	Title       string
+	Description string
+	Canonical   string
+	NoIndex     bool
+	Words       int
+	RenderTime  time.Duration
+	// Links are the checked internal links of the page.
+	Links []links.Link
+	// Redirect is the location of a page which redirects, Error the error of a failed render.
+	Redirect string
+	Error    string
+}
+
+// Issue is a problem found on a page.
+type Issue struct {
+	Kind   Kind   `json:"kind"`
+	URL    string `json:"url"`
+	Locale string `json:"locale,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the result of an audit run.
+type Report struct {
+	Started  time.Time    `json:"started"`
+	Finished time.Time    `json:"finished"`
+	Pages    int          `json:"pages"`
+	Counts   map[Kind]int `json:"counts"`
+	Issues   []Issue      `json:"issues"`
+}
+
+// Auditor collects the pages of a run.
+type Auditor struct {
+	config  Config
+	started time.Time
+	mutex   sync.Mutex
+	pages   []Page
+}
+
+// New starts an audit run.
+func New(config Config) *Auditor {
+	return &Auditor{config: config, started: time.Now()}
+}
+
+// Add audits a page.
+func (a *Auditor) Add(p Page) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.pages = append(a.pages, p)
+}
+
+// Report returns the issues of the pages added so far.
+func (a *Auditor) Report() *Report {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	r := &Report{Started: a.started, Finished: time.Now(), Pages: len(a.pages), Counts: map[Kind]int{}}
+	add := func(kind Kind, p Page, detail string) {
+		r.Issues = append(r.Issues, Issue{Kind: kind, URL: p.URL, Locale: p.Locale, Detail: detail})
+		r.Counts[kind]++
+	}
+
+	// duplicates are counted per locale, the variants of a page share its URL
+	titles := map[string][]string{}
+	descriptions := map[string][]string{}
+	for _, p := range a.pages {
+		if p.Error == "" && p.Redirect == "" && !p.NoIndex {
+			if p.Title != "" {
+				titles[p.Locale+"\n"+p.Title] = append(titles[p.Locale+"\n"+p.Title], p.URL)
+			}
+			if p.Description != "" {
+				descriptions[p.Locale+"\n"+p.Description] = append(descriptions[p.Locale+"\n"+p.Description], p.URL)
+			}
+		}
+	}
+
+	for _, p := range a.pages {
+		switch {
+		case p.Error != "":
+			add(RenderError, p, p.Error)
+			continue
+		case p.Redirect != "":
+			add(Redirect, p, p.Redirect)
+			continue
+		}
+
+		if p.NoIndex {
+			add(NoIndex, p, "")
+		}
+		if p.Words == 0 {
+			add(EmptyBody, p, "")
+		}
+		if p.RenderTime > a.config.slowRender() {
+			add(SlowRender, p, p.RenderTime.Round(time.Millisecond).String())
+		}
+		if p.Canonical != "" && normalize(p.Canonical) != normalize(p.URL) {
+			add(CanonicalMismatch, p, p.Canonical)
+		}
+		for _, l := range p.Links {
+			switch {
+			case l.Broken():
+				add(BrokenLink, p, linkDetail(l))
+			case len(l.Redirects) > 0:
+				add(RedirectedLink, p, linkDetail(l))
+			}
+		}
+		if p.NoIndex {
+			continue
+		}
+
+		if p.Title == "" {
+			add(MissingTitle, p, "")
+		} else if others := titles[p.Locale+"\n"+p.Title]; len(others) > 1 {
+			add(DuplicateTitle, p, duplicates(p.URL, others))
+		}
+		if p.Description == "" {
+			add(MissingDescription, p, "")
+		} else if others := descriptions[p.Locale+"\n"+p.Description]; len(others) > 1 {
+			add(DuplicateDescription, p, duplicates(p.URL, others))
+		}
+	}
+
+	sort.SliceStable(r.Issues, func(i, j int) bool { return r.Issues[i].Kind < r.Issues[j].Kind })
+	return r
+}
+
+// Write writes the report of the run as JSON and HTML into Config.Dir and returns the file paths.
+func (a *Auditor) Write() (string, string, error) {
+	r := a.Report()
+
+	if err := os.MkdirAll(a.config.dir(), 0755); err != nil {
+		return "", "", err
+	}
+	name := filepath.Join(a.config.dir(), "audit-"+r.Started.Format("20060102-150405"))
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	if err := ioutil.WriteFile(name+".json", b, 0644); err != nil {
+		return "", "", err
+	}
+
+	f, err := os.Create(name + ".html")
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	if err := r.WriteHTML(f); err != nil {
+		return "", "", err
+	}
+	return name + ".json", name + ".html", nil
+}
+
+// normalize makes equivalent URLs equal: no fragment, no trailing slash, lower case host.
+func normalize(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment = ""
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	return u.String()
+}
+
+// linkDetail describes the redirect chain and the outcome of a link.
+func linkDetail(l links.Link) string {
+	chain := strings.Join(append([]string{l.URL}, l.Redirects...), " -> ")
+	if l.Error != "" {
+		return chain + ": " + l.Error
+	}
+	return fmt.Sprintf("%s: %d", chain, l.Status)
+}
+
+func duplicates(self string, urls []string) string {
+	others := make([]string, 0, len(urls)-1)
+	for _, u := range urls {
+		if u != self {
+			others = append(others, u)
+		}
+	}
+	return fmt.Sprintf("same as %s", strings.Join(others, ", "))
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"bytes"
+	"github.com/goprerender/prerender/internal/links"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAuditor_Report(t *testing.T) {
+	a := New(Config{})
+	a.Add(Page{URL: "https://a.com/1", Title: "Shop", Description: "Shoes", Canonical: "https://a.com/1/", Words: 10, Links: []links.Link{
+		{URL: "https://a.com/2", Status: 200},
+		{URL: "https://a.com/7", Status: 404},
+		{URL: "https://a.com/8", Status: 200, Redirects: []string{"https://a.com/9"}},
+	}})
+	a.Add(Page{URL: "https://a.com/2", Title: "Shop", Canonical: "https://a.com/3", Words: 10, RenderTime: 6 * time.Second})
+	a.Add(Page{URL: "https://a.com/2", Locale: "de", Title: "Shop", Description: "Schuhe", Words: 10})
+	a.Add(Page{URL: "https://a.com/4", NoIndex: true})
+	a.Add(Page{URL: "https://a.com/5", Redirect: "https://a.com/6"})
+
+	r := a.Report()
+	assert.Equal(t, 5, r.Pages)
+	assert.Equal(t, map[Kind]int{
+		DuplicateTitle:     2,
+		MissingDescription: 1,
+		CanonicalMismatch:  1,
+		SlowRender:         1,
+		NoIndex:            1,
+		EmptyBody:          1,
+		Redirect:           1,
+		BrokenLink:         1,
+		RedirectedLink:     1,
+	}, r.Counts)
+
+	var html bytes.Buffer
+	assert.NoError(t, r.WriteHTML(&html))
+	assert.Contains(t, html.String(), "https://a.com/3")
+	assert.Contains(t, html.String(), "https://a.com/8 -&gt; https://a.com/9: 200")
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"html/template"
+	"io"
+)
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SEO audit {{.Started.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>SEO audit</h1>
+<p>{{.Pages}} pages, {{.Started.Format "2006-01-02 15:04:05"}} to {{.Finished.Format "15:04:05"}}</p>
+<table>
+<tr><th>Issue</th><th>Pages</th></tr>
+{{range $kind, $count := .Counts}}<tr><td>{{$kind}}</td><td>{{$count}}</td></tr>
+{{end}}</table>
+<h2>Issues</h2>
+<table>
+<tr><th>Issue</th><th>URL</th><th>Locale</th><th>Detail</th></tr>
+{{range .Issues}}<tr><td>{{.Kind}}</td><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Locale}}</td><td>{{.Detail}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a static HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
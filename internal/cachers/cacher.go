@@ -4,4 +4,7 @@ type Сacher interface {
 	Put(key string, data []byte) error
 	Get(key string) ([]byte, error)
 	Len() int
- }
+	// Swap stores data as the value of key only if its value is old, or if key is missing
+	// when old is nil. It reports whether data was stored.
+	Swap(key string, old, data []byte) (bool, error)
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"strings"
+)
+
+// maxDiffLines bounds the lines compared after the common head and tail are removed,
+// beyond it the changed part is shown as removed then added.
+const maxDiffLines = 2000
+
+// Diff returns the line diff of two HTML documents, lines are prefixed by "-" when removed,
+// "+" when added and " " when kept. Tags are put on their own lines first, rendered HTML
+// often has no line breaks.
+func Diff(a, b string) string {
+	x, y := lines(a), lines(b)
+
+	head := 0
+	for head < len(x) && head < len(y) && x[head] == y[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(x)-head && tail < len(y)-head && x[len(x)-1-tail] == y[len(y)-1-tail] {
+		tail++
+	}
+
+	var sb strings.Builder
+	write := func(prefix string, lines []string) {
+		for _, line := range lines {
+			sb.WriteString(prefix)
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	}
+
+	write(" ", x[:head])
+	mx, my := x[head:len(x)-tail], y[head:len(y)-tail]
+	if len(mx) > maxDiffLines || len(my) > maxDiffLines {
+		write("-", mx)
+		write("+", my)
+	} else {
+		diffLCS(mx, my, write)
+	}
+	write(" ", x[len(x)-tail:])
+	return sb.String()
+}
+
+// diffLCS writes the diff of x and y along their longest common subsequence.
+func diffLCS(x, y []string, write func(prefix string, lines []string)) {
+	// lcs[i][j] is the LCS length of x[i:] and y[j:]
+	lcs := make([][]int32, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			write(" ", x[i:i+1])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			write("-", x[i:i+1])
+			i++
+		default:
+			write("+", y[j:j+1])
+			j++
+		}
+	}
+	write("-", x[i:])
+	write("+", y[j:])
+}
+
+func lines(s string) []string {
+	s = strings.ReplaceAll(s, "><", ">\n<")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
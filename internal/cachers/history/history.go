@@ -0,0 +1,167 @@
+// Package history keeps the last versions of the cached pages.
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/goprerender/prerender/internal/archive"
+	"github.com/goprerender/prerender/internal/cachers"
+	"time"
+)
+
+var (
+	ErrVersionNotFound = errors.New("error: version not found")
+	// ErrConflict is returned when other writers kept changing the versions of a key.
+	ErrConflict = errors.New("error: versions changed concurrently")
+)
+
+// Version describes a stored version of a key.
+type Version struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Hash is the SHA-256 of the content, not of its compressed form.
+	Hash string `json:"hash"`
+	Size int    `json:"size"`
+}
+
+// History stores values in a cacher and keeps their last versions next to them.
+// The list of versions of a key is stored under key+":versions", newest first, and
+// each version under key+":v<n>" where n cycles through the kept slots. The list is
+// changed with a compare-and-swap of the cacher, so processes sharing the storage can
+// add versions of the same key at once.
+type History struct {
+	c        cachers.Сacher
+	versions int
+}
+
+func New(c cachers.Сacher, versions int) *History {
+	return &History{c: c, versions: versions}
+}
+
+// maxSwaps is how many times Put reads the list again when another writer changed it.
+const maxSwaps = 5
+
+// Put stores data as the value of key. A new version is kept only if the content changed,
+// which is reported.
+func (h *History) Put(key string, data []byte) (bool, error) {
+	v := Version{
+		Hash: ContentHash(data),
+		Size: len(data),
+	}
+	for i := 0; i < maxSwaps; i++ {
+		old, err := h.c.Get(key + versionsKey)
+		if err != nil {
+			old = nil
+		}
+		list, err := parseList(old)
+		if err != nil {
+			return false, err
+		}
+		if len(list) > 0 && list[0].Hash == v.Hash {
+			return false, h.c.Put(key, data)
+		}
+
+		v.ID = 0
+		if len(list) > 0 {
+			v.ID = list[0].ID + 1
+		}
+		v.CreatedAt = time.Now()
+		list = append([]Version{v}, list...)
+		if len(list) > h.versions {
+			list = list[:h.versions]
+		}
+		b, err := json.Marshal(list)
+		if err != nil {
+			return false, err
+		}
+
+		swapped, err := h.c.Swap(key+versionsKey, old, b)
+		if err != nil {
+			return false, err
+		}
+		if !swapped {
+			continue
+		}
+		// the slot is written once the version is listed, Get checks its hash meanwhile
+		if err := h.c.Put(h.versionKey(key, v.ID), data); err != nil {
+			return true, err
+		}
+		return true, h.c.Put(key, data)
+	}
+	return false, ErrConflict
+}
+
+// Versions returns the kept versions of key, newest first.
+func (h *History) Versions(key string) ([]Version, error) {
+	return h.list(key)
+}
+
+// Get returns the data of a version of key.
+func (h *History) Get(key string, id int) ([]byte, error) {
+	list, err := h.list(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range list {
+		if v.ID != id {
+			continue
+		}
+		data, err := h.c.Get(h.versionKey(key, id))
+		if err != nil || ContentHash(data) != v.Hash {
+			// the slot is not written yet, or already reused by a newer version
+			return nil, ErrVersionNotFound
+		}
+		return data, nil
+	}
+	return nil, ErrVersionNotFound
+}
+
+// Restore makes a version of key its value again, as a new version.
+func (h *History) Restore(key string, id int) error {
+	data, err := h.Get(key, id)
+	if err != nil {
+		return err
+	}
+	_, err = h.Put(key, data)
+	return err
+}
+
+const versionsKey = ":versions"
+
+func (h *History) list(key string) ([]Version, error) {
+	b, err := h.c.Get(key + versionsKey)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+	return parseList(b)
+}
+
+// parseList parses a stored list of versions, nil is the list of a key without versions.
+func parseList(b []byte) ([]Version, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var list []Version
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// versionKey reuses the slots of the versions which are not kept anymore.
+func (h *History) versionKey(key string, id int) string {
+	return fmt.Sprintf("%s:v%d", key, id%(h.versions+1))
+}
+
+// ContentHash hashes the comment and the uncompressed content of snapshots, so the same page
+// stored twice, or with another codec, gets the same hash.
+func ContentHash(data []byte) string {
+	if s, err := archive.Unpack(data); err == nil {
+		if content, err := s.Content(); err == nil {
+			data = append([]byte(s.Comment+"\n"), content...)
+		}
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
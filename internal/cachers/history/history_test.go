@@ -0,0 +1,148 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type memory map[string][]byte
+
+func (m memory) Put(key string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func (m memory) Get(key string) ([]byte, error) {
+	if data, ok := m[key]; ok {
+		return data, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m memory) Len() int {
+	return len(m)
+}
+
+func (m memory) Swap(key string, old, data []byte) (bool, error) {
+	current, ok := m[key]
+	if ok != (old != nil) || ok && !bytes.Equal(current, old) {
+		return false, nil
+	}
+	m[key] = data
+	return true, nil
+}
+
+// shared is a memory used by several writers, like the storage service.
+type shared struct {
+	mutex sync.Mutex
+	m     memory
+}
+
+func (s *shared) Put(key string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Put(key, data)
+}
+
+func (s *shared) Get(key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Get(key)
+}
+
+func (s *shared) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Len()
+}
+
+func (s *shared) Swap(key string, old, data []byte) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Swap(key, old, data)
+}
+
+func TestHistory(t *testing.T) {
+	m := memory{}
+	h := New(m, 2)
+
+	changed, err := h.Put("key", []byte("a"))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, _ = h.Put("key", []byte("a"))
+	assert.False(t, changed)
+
+	h.Put("key", []byte("b"))
+	h.Put("key", []byte("c"))
+
+	versions, err := h.Versions("key")
+	assert.NoError(t, err)
+	assert.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].ID)
+	assert.Equal(t, 1, versions[1].ID)
+
+	data, err := h.Get("key", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("b"), data)
+
+	_, err = h.Get("key", 0)
+	assert.Equal(t, ErrVersionNotFound, err)
+
+	hash := versions[1].Hash
+	assert.NoError(t, h.Restore("key", 1))
+	assert.Equal(t, []byte("b"), m["key"])
+	versions, _ = h.Versions("key")
+	assert.Equal(t, 3, versions[0].ID)
+	assert.Equal(t, hash, versions[0].Hash)
+}
+
+func TestHistory_concurrent(t *testing.T) {
+	s := &shared{m: memory{}}
+
+	// every writer has its own History, like the processes sharing the storage
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			changed, err := New(s, 5).Put("key", []byte(fmt.Sprint(i)))
+			assert.NoError(t, err)
+			assert.True(t, changed)
+		}(i)
+	}
+	wg.Wait()
+
+	h := New(s, 5)
+	versions, err := h.Versions("key")
+	assert.NoError(t, err)
+	assert.Len(t, versions, 3)
+	for i, v := range versions {
+		assert.Equal(t, 2-i, v.ID)
+		data, err := h.Get("key", v.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, v.Hash, ContentHash(data))
+	}
+}
+
+func TestHistory_Get_reusedSlot(t *testing.T) {
+	m := memory{}
+	h := New(m, 1)
+	h.Put("key", []byte("a"))
+	h.Put("key", []byte("b"))
+
+	// version 1 is listed but its slot still holds version 0 of the same slot
+	m[h.versionKey("key", 1)] = []byte("a")
+	_, err := h.Get("key", 1)
+	assert.Equal(t, ErrVersionNotFound, err)
+}
+
+func TestDiff(t *testing.T) {
+	assert.Equal(t, " <p>\n-a\n+b\n </p>\n", Diff("<p>\na\n</p>", "<p>\nb\n</p>"))
+	assert.Equal(t, " <div>\n+<i>\n+</i>\n <b>\n </b>\n", Diff("<div><b></b>", "<div><i></i><b></b>"))
+	assert.Equal(t, "-<a>\n+<b>\n", Diff("<a>", "<b>"))
+}
@@ -1,21 +1,26 @@
 package inmemory
 
 import (
+	"bytes"
 	"github.com/bluele/gcache"
 	"github.com/goprerender/prerender/internal/cachers"
+	"sync"
 	"time"
 )
 
 type repository struct {
-	gc gcache.Cache
-	dd time.Duration
+	gc    gcache.Cache
+	dd    time.Duration
+	mutex *sync.Mutex
 }
 
 func New(gc gcache.Cache) cachers.Сacher {
-	return repository{gc: gc, dd: time.Hour * 24 * 7}
+	return repository{gc: gc, dd: time.Hour * 24 * 7, mutex: &sync.Mutex{}}
 }
 
 func (r repository) Put(key string, data []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	return r.gc.SetWithExpire(key, data, r.dd)
 }
 
@@ -30,3 +35,14 @@ func (r repository) Get(key string) ([]byte, error) {
 func (r repository) Len() int {
 	return r.gc.Len(true)
 }
+
+func (r repository) Swap(key string, old, data []byte) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	value, err := r.gc.Get(key)
+	if exists := err == nil; exists != (old != nil) || exists && !bytes.Equal(value.([]byte), old) {
+		return false, nil
+	}
+	return true, r.gc.SetWithExpire(key, data, r.dd)
+}
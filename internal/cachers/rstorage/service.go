@@ -54,3 +54,19 @@ func (s server) Len() int {
 	}
 	return int(result.GetLength())
 }
+
+func (s server) Swap(key string, old, data []byte) (bool, error) {
+	ctx := context.Background()
+	now, _ := ptypes.TimestampProto(time.Now())
+	req := storage.SwapRequest{Api: "v1", Page: &storage.Page{
+		Hash:      key,
+		Data:      data,
+		CreatedAt: now,
+	}, Old: old, Exists: old != nil}
+	result, err := s.gw.Swap(ctx, &req)
+	if err != nil {
+		s.logger.Error(err)
+		return false, err
+	}
+	return result.GetSwapped(), nil
+}
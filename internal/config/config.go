@@ -0,0 +1,63 @@
+// Package config loads the prerender configuration file.
+package config
+
+import (
+	"encoding/json"
+	"github.com/goprerender/prerender/internal/audit"
+	"github.com/goprerender/prerender/internal/links"
+	"github.com/goprerender/prerender/pkg/executor"
+	"github.com/goprerender/prerender/pkg/log"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Config is the application configuration shared by the server and the worker.
+type Config struct {
+	Renderer renderer.Config `json:"renderer"`
+	Executor executor.Config `json:"executor"`
+	Server   ServerConfig    `json:"server"`
+	Sitemap  SitemapConfig   `json:"sitemap"`
+	Audit    audit.Config    `json:"audit"`
+	Links    links.Config    `json:"links"`
+}
+
+// ServerConfig holds the settings of the HTTP server.
+type ServerConfig struct {
+	// MaxAge is the max-age of the Cache-Control header of the pages, one hour by default.
+	MaxAge renderer.Duration `json:"maxAge"`
+	// AdminToken is the bearer token of the /admin endpoints, they are disabled without one.
+	AdminToken string `json:"adminToken"`
+}
+
+// SitemapConfig sets when the worker refreshes the pages of the sitemaps.
+type SitemapConfig struct {
+	// Schedule is a cron spec, "01 00 * * *" by default, "" disables the scheduled refresh.
+	Schedule string `json:"schedule"`
+	// OnStart refreshes the sitemaps when the worker starts, true by default.
+	OnStart bool `json:"onStart"`
+}
+
+// Load reads the configuration from a JSON file. A missing file yields the default configuration.
+func Load(file string, logger log.Logger) (*Config, error) {
+	c := &Config{
+		Server:  ServerConfig{MaxAge: renderer.Duration(time.Hour)},
+		Sitemap: SitemapConfig{Schedule: "01 00 * * *", OnStart: true},
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Warnf("Config file %s not found, using defaults", file)
+			return c, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,232 @@
+// Package links checks the links of the rendered pages.
+package links
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxRedirects = 10
+
+// ErrTooManyRedirects is the error of links redirecting more than 10 times.
+var ErrTooManyRedirects = errors.New("error: too many redirects")
+
+// metrics counts the links checked by the process, they are published at /debug/vars.
+var metrics = expvar.NewMap("links")
+
+// Config holds the link checker settings.
+type Config struct {
+	// Concurrency is the number of links checked at the same time, 8 by default.
+	Concurrency int `json:"concurrency"`
+	// Timeout of a request, 10s by default.
+	Timeout renderer.Duration `json:"timeout"`
+	// CacheTTL is how long the status of a link is reused, 1h by default.
+	CacheTTL renderer.Duration `json:"cacheTtl"`
+}
+
+func (c Config) concurrency() int {
+	if c.Concurrency <= 0 {
+		return 8
+	}
+	return c.Concurrency
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.Timeout)
+}
+
+func (c Config) cacheTTL() time.Duration {
+	if c.CacheTTL <= 0 {
+		return time.Hour
+	}
+	return time.Duration(c.CacheTTL)
+}
+
+// Link is the status of a link.
+type Link struct {
+	URL string `json:"url"`
+	// Status is the status of the last response, after the redirects.
+	Status int `json:"status,omitempty"`
+	// Redirects are the locations the link redirected to, in order.
+	Redirects []string `json:"redirects,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Broken reports whether the link fails or ends with an HTTP error.
+func (l Link) Broken() bool {
+	return l.Error != "" || l.Status >= 400
+}
+
+// Hosts readies the requests to a host with its settings, e.g. its credentials and rate limit.
+type Hosts interface {
+	Prepare(req *http.Request) (release func(), err error)
+}
+
+// Checker checks links with HEAD requests.
+type Checker struct {
+	config Config
+	hosts  Hosts
+	client *http.Client
+	sem    chan struct{}
+	mutex  sync.Mutex
+	cache  map[string]cached
+}
+
+type cached struct {
+	link      Link
+	checkedAt time.Time
+}
+
+// NewChecker returns a Checker, the redirects are followed by hand to record them. The requests
+// are prepared by hosts, nil sends them as they are.
+func NewChecker(config Config, hosts Hosts) *Checker {
+	return &Checker{
+		config: config,
+		hosts:  hosts,
+		client: &http.Client{
+			Timeout: config.timeout(),
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		sem:   make(chan struct{}, config.concurrency()),
+		cache: map[string]cached{},
+	}
+}
+
+// Internal returns the links to the host of pageURL.
+func Internal(pageURL string, links []string) []string {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var internal []string
+	for _, link := range links {
+		if u, err := url.Parse(link); err == nil && strings.EqualFold(u.Hostname(), page.Hostname()) {
+			internal = append(internal, link)
+		}
+	}
+	return internal
+}
+
+// Prune removes the statuses older than the cache TTL.
+func (c *Checker) Prune() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for link, cl := range c.cache {
+		if time.Since(cl.checkedAt) >= c.config.cacheTTL() {
+			delete(c.cache, link)
+		}
+	}
+}
+
+// Check returns the status of the links, in order.
+func (c *Checker) Check(links []string) []Link {
+	res := make([]Link, len(links))
+	var wg sync.WaitGroup
+	for i, link := range links {
+		wg.Add(1)
+		go func(i int, link string) {
+			defer wg.Done()
+			res[i] = c.link(link)
+		}(i, link)
+	}
+	wg.Wait()
+	return res
+}
+
+func (c *Checker) link(link string) Link {
+	c.mutex.Lock()
+	cl, ok := c.cache[link]
+	c.mutex.Unlock()
+	if ok && time.Since(cl.checkedAt) < c.config.cacheTTL() {
+		metrics.Add("cached", 1)
+		return cl.link
+	}
+
+	c.sem <- struct{}{}
+	l := c.check(link)
+	<-c.sem
+
+	metrics.Add("checked", 1)
+	if l.Broken() {
+		metrics.Add("broken", 1)
+	}
+	if len(l.Redirects) > 0 {
+		metrics.Add("redirects", 1)
+	}
+
+	c.mutex.Lock()
+	c.cache[link] = cached{link: l, checkedAt: time.Now()}
+	c.mutex.Unlock()
+	return l
+}
+
+func (c *Checker) check(link string) Link {
+	l := Link{URL: link}
+	next := link
+	for i := 0; i <= maxRedirects; i++ {
+		status, location, err := c.head(next)
+		if err != nil {
+			l.Error = err.Error()
+			return l
+		}
+		l.Status = status
+		if status < 300 || status >= 400 || location == "" {
+			return l
+		}
+		l.Redirects = append(l.Redirects, location)
+		next = location
+	}
+	l.Error = ErrTooManyRedirects.Error()
+	return l
+}
+
+// head returns the status and the absolute redirect location of link. Servers refusing
+// HEAD are asked with GET.
+func (c *Checker) head(link string) (int, string, error) {
+	status, location, err := c.do(http.MethodHead, link)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, location, err = c.do(http.MethodGet, link)
+	}
+	return status, location, err
+}
+
+// do sends a request prepared for the host of link.
+func (c *Checker) do(method, link string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	if c.hosts != nil {
+		release, err := c.hosts.Prepare(req)
+		if err != nil {
+			return 0, "", err
+		}
+		defer release()
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	var location string
+	if u, err := resp.Location(); err == nil {
+		location = u.String()
+	}
+	return resp.StatusCode, location, nil
+}
@@ -0,0 +1,85 @@
+package links
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker_Check(t *testing.T) {
+	var heads int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			atomic.AddInt32(&heads, 1)
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/get":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewChecker(Config{Concurrency: 2}, nil)
+	res := c.Check([]string{ts.URL + "/ok", ts.URL + "/old", ts.URL + "/missing", ts.URL + "/get"})
+
+	assert.Equal(t, Link{URL: ts.URL + "/ok", Status: 200}, res[0])
+	assert.Equal(t, Link{URL: ts.URL + "/old", Status: 200, Redirects: []string{ts.URL + "/new", ts.URL + "/ok"}}, res[1])
+	assert.True(t, res[2].Broken())
+	assert.False(t, res[3].Broken())
+
+	// the status is cached
+	c.Check([]string{ts.URL + "/ok"})
+	assert.Equal(t, int32(2), atomic.LoadInt32(&heads))
+}
+
+func TestInternal(t *testing.T) {
+	links := []string{"https://example.com/a", "https://www.example.com/b", "http://EXAMPLE.com/c"}
+	assert.Equal(t, []string{"https://example.com/a", "http://EXAMPLE.com/c"}, Internal("https://example.com/", links))
+}
+
+// hosts adds a token to the requests and counts the ones not released
+type hosts struct {
+	running int32
+}
+
+func (h *hosts) Prepare(req *http.Request) (func(), error) {
+	req.Header.Set("Authorization", "Bearer secret")
+	atomic.AddInt32(&h.running, 1)
+	return func() { atomic.AddInt32(&h.running, -1) }, nil
+}
+
+func TestChecker_Check_hosts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	h := &hosts{}
+	res := NewChecker(Config{}, h).Check([]string{ts.URL + "/a"})
+	assert.Equal(t, Link{URL: ts.URL + "/a", Status: 200}, res[0])
+	assert.Equal(t, int32(0), atomic.LoadInt32(&h.running))
+
+	res = NewChecker(Config{}, nil).Check([]string{ts.URL + "/a"})
+	assert.True(t, res[0].Broken())
+}
+
+func TestChecker_Prune(t *testing.T) {
+	c := NewChecker(Config{}, nil)
+	c.cache["old"] = cached{checkedAt: time.Now().Add(-2 * time.Hour)}
+	c.cache["new"] = cached{checkedAt: time.Now()}
+	c.Prune()
+	assert.Len(t, c.cache, 1)
+	assert.Contains(t, c.cache, "new")
+}
@@ -0,0 +1,179 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/goprerender/prerender/pkg/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxDepth is how deep sitemap indexes are followed, the sitemaps of sitemaps.json are at 0.
+	maxDepth = 3
+	// maxSitemapSize is the largest uncompressed sitemap allowed by the protocol.
+	maxSitemapSize = 50 << 20
+)
+
+// ErrNotSitemap is returned for documents which are neither a urlset nor a sitemapindex.
+var ErrNotSitemap = errors.New("error: not a sitemap")
+
+// document is a urlset or a sitemapindex.
+type document struct {
+	XMLName  xml.Name
+	URLs     []loc `xml:"url"`
+	Sitemaps []loc `xml:"sitemap"`
+}
+
+type loc struct {
+	Loc string `xml:"loc"`
+}
+
+// discoverer finds the page URLs of sitemaps. Sitemaps and pages are visited once per run,
+// across all the entries of sitemaps.json.
+type discoverer struct {
+	client   *http.Client
+	sitemaps map[string]bool
+	pages    map[string]bool
+	logger   log.Logger
+}
+
+func newDiscoverer(logger log.Logger) *discoverer {
+	return &discoverer{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		sitemaps: map[string]bool{},
+		pages:    map[string]bool{},
+		logger:   logger,
+	}
+}
+
+// Pages returns the page URLs of entry not returned before. entry is a sitemap, or a sitemap
+// index, URL or a bare host like "example.com" whose sitemaps are listed in its robots.txt.
+func (d *discoverer) Pages(entry string) []string {
+	u, err := url.Parse(entry)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("https://" + entry)
+		if err != nil {
+			d.logger.Error("Sitemap entry error: ", err)
+			return nil
+		}
+	}
+
+	var pages []string
+	if u.Path != "" && u.Path != "/" {
+		return d.visit(u.String(), 0, pages)
+	}
+
+	sitemaps, err := d.robots(u)
+	if err != nil {
+		d.logger.Warnf("No robots.txt for %s: %s", u.Host, err)
+	}
+	if len(sitemaps) == 0 {
+		sitemaps = []string{u.Scheme + "://" + u.Host + "/sitemap.xml"}
+	}
+	for _, sitemap := range sitemaps {
+		pages = d.visit(sitemap, 0, pages)
+	}
+	return pages
+}
+
+// visit appends the new pages of the sitemap to pages, following indexes down to maxDepth.
+func (d *discoverer) visit(sitemapURL string, depth int, pages []string) []string {
+	if d.sitemaps[sitemapURL] {
+		return pages
+	}
+	if depth > maxDepth {
+		d.logger.Warnf("Sitemap index too deep, skipped: %s", sitemapURL)
+		return pages
+	}
+	d.sitemaps[sitemapURL] = true
+
+	doc, err := d.fetch(sitemapURL)
+	if err != nil {
+		d.logger.Error("Get Sitemap: ", err, ", url: ", sitemapURL)
+		return pages
+	}
+
+	for _, s := range doc.Sitemaps {
+		pages = d.visit(strings.TrimSpace(s.Loc), depth+1, pages)
+	}
+	for _, p := range doc.URLs {
+		page := strings.TrimSpace(p.Loc)
+		if page == "" || d.pages[page] {
+			continue
+		}
+		d.pages[page] = true
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// fetch reads a sitemap, gzip compressed sitemaps (.xml.gz) are decompressed.
+func (d *discoverer) fetch(sitemapURL string) (*document, error) {
+	b, err := d.get(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		if b, err = ioutil.ReadAll(io.LimitReader(r, maxSitemapSize)); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc document
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, ErrNotSitemap
+	}
+	return &doc, nil
+}
+
+// robots returns the sitemaps of the Sitemap lines of the robots.txt of the host.
+func (d *discoverer) robots(u *url.URL) ([]string, error) {
+	b, err := d.get(u.Scheme + "://" + u.Host + "/robots.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	var sitemaps []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		i := strings.Index(line, ":")
+		if i < 0 || !strings.EqualFold(strings.TrimSpace(line[:i]), "sitemap") {
+			continue
+		}
+		if sitemap := strings.TrimSpace(line[i+1:]); sitemap != "" {
+			sitemaps = append(sitemaps, sitemap)
+		}
+	}
+	return sitemaps, scanner.Err()
+}
+
+func (d *discoverer) get(rawURL string) ([]byte, error) {
+	resp, err := d.client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: %s: %s", rawURL, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
+}
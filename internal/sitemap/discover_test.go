@@ -0,0 +1,70 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/goprerender/prerender/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func urlset(base string, paths ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, p := range paths {
+		fmt.Fprintf(&b, "<url><loc>%s%s</loc></url>", base, p)
+	}
+	b.WriteString("</urlset>")
+	return b.String()
+}
+
+func index(base string, paths ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, p := range paths {
+		fmt.Fprintf(&b, "<sitemap><loc>%s%s</loc></sitemap>", base, p)
+	}
+	b.WriteString("</sitemapindex>")
+	return b.String()
+}
+
+func TestDiscoverer_Pages(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := ts.URL
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /admin\nsitemap: %s/index.xml\n", base)
+		case "/index.xml":
+			fmt.Fprint(w, index(base, "/a.xml.gz", "/b.xml", "/index.xml", "/1.xml"))
+		case "/a.xml.gz":
+			var gz bytes.Buffer
+			zw := gzip.NewWriter(&gz)
+			fmt.Fprint(zw, urlset(base, "/a", "/b"))
+			zw.Close()
+			w.Write(gz.Bytes())
+		case "/b.xml":
+			fmt.Fprint(w, urlset(base, "/b", "/c"))
+		case "/1.xml", "/2.xml", "/3.xml":
+			// 3.xml is at depth 3, the 4.xml it lists is past maxDepth and skipped
+			next := int(r.URL.Path[1]-'0') + 1
+			fmt.Fprint(w, index(base, fmt.Sprintf("/%d.xml", next)))
+		case "/4.xml":
+			fmt.Fprint(w, urlset(base, "/deep"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	logger, _ := log.NewForTest()
+	d := newDiscoverer(logger)
+
+	assert.Equal(t, []string{ts.URL + "/a", ts.URL + "/b", ts.URL + "/c"}, d.Pages(ts.URL))
+	// the pages are returned once per run
+	assert.Len(t, d.Pages(ts.URL+"/b.xml"), 0)
+}
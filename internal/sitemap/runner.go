@@ -0,0 +1,58 @@
+package sitemap
+
+import (
+	"github.com/goprerender/prerender/internal/audit"
+	"github.com/goprerender/prerender/internal/links"
+	"github.com/goprerender/prerender/pkg/executor"
+	"github.com/goprerender/prerender/pkg/log"
+	"sync/atomic"
+)
+
+// Runner refreshes the sitemaps in the process serving the crawlers, so the refresh renders go
+// through the same render scheduler and host rate limits as the crawler requests.
+// One refresh runs at a time.
+type Runner struct {
+	e       *executor.Executor
+	config  audit.Config
+	checker *links.Checker
+	logger  log.Logger
+	running int32
+}
+
+func NewRunner(e *executor.Executor, config audit.Config, checker *links.Checker, logger log.Logger) *Runner {
+	return &Runner{
+		e:       e,
+		config:  config,
+		checker: checker,
+		logger:  logger,
+	}
+}
+
+// Run refreshes the sitemaps, it returns false without doing anything when a refresh is running.
+func (r *Runner) Run(force bool) bool {
+	if !atomic.CompareAndSwapInt32(&r.running, 0, 1) {
+		r.logger.Warn("Sitemap refresh already running")
+		return false
+	}
+	defer atomic.StoreInt32(&r.running, 0)
+
+	BySitemap(r.e, force, r.config, r.checker, r.logger)
+	return true
+}
+
+// Start is Run in the background.
+func (r *Runner) Start(force bool) bool {
+	if !atomic.CompareAndSwapInt32(&r.running, 0, 1) {
+		return false
+	}
+	go func() {
+		defer atomic.StoreInt32(&r.running, 0)
+		BySitemap(r.e, force, r.config, r.checker, r.logger)
+	}()
+	return true
+}
+
+// Running reports whether a refresh is running.
+func (r *Runner) Running() bool {
+	return atomic.LoadInt32(&r.running) == 1
+}
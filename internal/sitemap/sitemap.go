@@ -2,13 +2,21 @@ package sitemap
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/goprerender/prerender/internal/audit"
+	"github.com/goprerender/prerender/internal/links"
 	"github.com/goprerender/prerender/pkg/executor"
 	"github.com/goprerender/prerender/pkg/log"
-	"github.com/yterajima/go-sitemap"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"github.com/goprerender/prerender/pkg/seo"
 	"io/ioutil"
+	"time"
 )
 
-func BySitemap(r *executor.Executor, force bool, logger log.Logger) {
+// BySitemap renders the pages of the sitemaps listed in sitemaps.json, sitemap URLs or hosts, and
+// writes an audit report of them, their internal links are checked with checker.
+func BySitemap(r *executor.Executor, force bool, config audit.Config, checker *links.Checker, logger log.Logger) {
 	type sitemaps []string
 
 	f, err := ioutil.ReadFile("sitemaps.json")
@@ -23,28 +31,96 @@ func BySitemap(r *executor.Executor, force bool, logger log.Logger) {
 		logger.Errorf("Error parsing sitemap.json")
 	}
 
+	// the link statuses expired since the last run are dropped
+	checker.Prune()
+
+	a := audit.New(config)
+	d := newDiscoverer(logger)
 	for _, j := range sitemapUrls {
-		doSitemap(r, force, j, logger)
+		doSitemap(r, force, j, d, a, checker, logger)
 	}
-}
 
-func doSitemap(e *executor.Executor, force bool, sitemapUrl string, logger log.Logger) {
-	siteMap, err := sitemap.Get(sitemapUrl, nil)
+	jsonReport, htmlReport, err := a.Write()
 	if err != nil {
-		logger.Error("Get Sitemap: ", err)
+		logger.Error("Audit report error: ", err)
+		return
+	}
+	logger.Infof("Audit report: %s, %s", jsonReport, htmlReport)
+}
+
+func doSitemap(e *executor.Executor, force bool, sitemapUrl string, d *discoverer, a *audit.Auditor, checker *links.Checker, logger log.Logger) {
+	pages := d.Pages(sitemapUrl)
+
+	logger.Infof("Sitemap len: %d", len(pages))
+
+	changed, unchanged := e.Changes()
+
+	for _, page := range pages {
+		logger.Info("SM URL: ", page)
+
+		// every locale variant is cached, "" is the page of hosts without locales
+		locales := e.Locales(page)
+		if len(locales) == 0 {
+			locales = []string{""}
+		}
+
+		for _, locale := range locales {
+			res, err := e.Execute(page, force, locale, renderer.Background)
+			var re *renderer.RedirectError
+			if errors.As(err, &re) {
+				logger.Infof("Sitemap URL redirects: %s -> %s", page, re.Location)
+				a.Add(audit.Page{URL: page, Locale: locale, Redirect: re.Location})
+				continue
+			}
+			if err != nil {
+				logger.Error("Sitemap Renderer error: ", err, ", url: ", page, ", locale: ", locale)
+				a.Add(audit.Page{URL: page, Locale: locale, Error: err.Error()})
+				continue
+			}
+			a.Add(auditPage(e, checker, page, locale, res))
+		}
 	}
 
-	logger.Infof("Sitemap len: %d", len(siteMap.URL))
+	c, u := e.Changes()
+	logger.Infof("Finished %s, Cache len: %d, content changed: %d, unchanged: %d",
+		sitemapUrl, e.GetPC().Len(), c-changed, u-unchanged)
+}
+
+// auditPage returns the audited part of a rendered page from its cached SEO metadata,
+// pages cached without it are parsed again. A stale copy served instead of a failed render
+// is a render error.
+func auditPage(e *executor.Executor, checker *links.Checker, pageURL, locale string, res *executor.Result) audit.Page {
+	if res.Cache == executor.CacheStale {
+		return audit.Page{URL: pageURL, Locale: locale, Error: staleDetail(res.Age())}
+	}
 
-	for _, URL := range siteMap.URL {
-		logger.Info("SM URL: ", URL.Loc)
+	p := audit.Page{URL: pageURL, Locale: locale, NoIndex: res.Page.NoIndex(), RenderTime: res.RenderTime()}
 
-		_, err := e.Execute(URL.Loc, force)
+	meta, err := e.SEO(pageURL, locale)
+	if err != nil {
+		html, err := res.Page.HTML()
 		if err != nil {
-			logger.Error("Sitemap Renderer error: ", err, ", url: ", URL.Loc)
-			continue
+			p.Error = err.Error()
+			return p
+		}
+		if meta, err = seo.Parse(html, pageURL); err != nil {
+			p.Error = err.Error()
+			return p
 		}
 	}
 
-	logger.Infof("Finished %s, Cache len: %d", sitemapUrl, e.GetPC().Len())
+	p.Title = meta.Title
+	p.Description = meta.Description
+	p.Canonical = meta.Canonical
+	p.Words = meta.Words
+	p.Links = checker.Check(links.Internal(pageURL, meta.Links))
+	return p
+}
+
+// staleDetail describes a stale copy rendered age ago, age is zero if it is not known.
+func staleDetail(age time.Duration) string {
+	if age == 0 {
+		return "stale copy served"
+	}
+	return fmt.Sprintf("stale copy served, rendered %s ago", age.Round(time.Second))
 }
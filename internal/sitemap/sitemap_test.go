@@ -0,0 +1,12 @@
+package sitemap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_staleDetail(t *testing.T) {
+	assert.Equal(t, "stale copy served, rendered 2h0m3s ago", staleDetail(2*time.Hour+3*time.Second+400*time.Millisecond))
+	assert.Equal(t, "stale copy served", staleDetail(0))
+}
@@ -23,6 +23,8 @@ type StorageClient interface {
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetReplay, error)
 	//
 	Len(ctx context.Context, in *LenRequest, opts ...grpc.CallOption) (*LenReplay, error)
+	// Stores a page only if its current data is the expected one
+	Swap(ctx context.Context, in *SwapRequest, opts ...grpc.CallOption) (*SwapReply, error)
 }
 
 type storageClient struct {
@@ -60,6 +62,15 @@ func (c *storageClient) Len(ctx context.Context, in *LenRequest, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *storageClient) Swap(ctx context.Context, in *SwapRequest, opts ...grpc.CallOption) (*SwapReply, error) {
+	out := new(SwapReply)
+	err := c.cc.Invoke(ctx, "/storage.Storage/Swap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StorageServer is the server API for Storage service.
 // All implementations must embed UnimplementedStorageServer
 // for forward compatibility
@@ -70,6 +81,8 @@ type StorageServer interface {
 	Get(context.Context, *GetRequest) (*GetReplay, error)
 	//
 	Len(context.Context, *LenRequest) (*LenReplay, error)
+	// Stores a page only if its current data is the expected one
+	Swap(context.Context, *SwapRequest) (*SwapReply, error)
 	mustEmbedUnimplementedStorageServer()
 }
 
@@ -86,6 +99,9 @@ func (UnimplementedStorageServer) Get(context.Context, *GetRequest) (*GetReplay,
 func (UnimplementedStorageServer) Len(context.Context, *LenRequest) (*LenReplay, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Len not implemented")
 }
+func (UnimplementedStorageServer) Swap(context.Context, *SwapRequest) (*SwapReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Swap not implemented")
+}
 func (UnimplementedStorageServer) mustEmbedUnimplementedStorageServer() {}
 
 // UnsafeStorageServer may be embedded to opt out of forward compatibility for this service.
@@ -153,6 +169,24 @@ func _Storage_Len_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Storage_Swap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Swap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storage.Storage/Swap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Swap(ctx, req.(*SwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Storage_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "storage.Storage",
 	HandlerType: (*StorageServer)(nil),
@@ -169,6 +203,10 @@ var _Storage_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Len",
 			Handler:    _Storage_Len_Handler,
 		},
+		{
+			MethodName: "Swap",
+			Handler:    _Storage_Swap_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/storage.proto",
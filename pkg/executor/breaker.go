@@ -0,0 +1,134 @@
+package executor
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCircuitOpen    = errors.New("error: origin host failing, circuit open")
+	ErrRecentlyFailed = errors.New("error: render failed recently")
+)
+
+// breaker is a circuit breaker per origin host. After Failures renders failed in a row the
+// circuit opens and renders of the host fail fast. Once OpenFor elapsed a single probe render
+// is let through (half-open), its success closes the circuit and its failure opens it again.
+type breaker struct {
+	mutex  sync.Mutex
+	config BreakerConfig
+	hosts  map[string]*circuit
+}
+
+type circuit struct {
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(config BreakerConfig) *breaker {
+	return &breaker{
+		config: config,
+		hosts:  map[string]*circuit{},
+	}
+}
+
+// allow reports whether a render of the host may run.
+func (b *breaker) allow(host string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c, ok := b.hosts[host]
+	if !ok || c.failures < b.config.failures() {
+		return true
+	}
+	if c.probing || time.Since(c.openedAt) < b.config.openFor() {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+// done records the outcome of a render of the host.
+func (b *breaker) done(host string, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err == nil {
+		delete(b.hosts, host)
+		return
+	}
+
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+	c.failures++
+	c.probing = false
+	if c.failures >= b.config.failures() {
+		c.openedAt = time.Now()
+	}
+}
+
+// cancel lets another probe through when the probe render of the host never started,
+// e.g. it was refused by the rate limit of the host.
+func (b *breaker) cancel(host string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if c, ok := b.hosts[host]; ok {
+		c.probing = false
+	}
+}
+
+// failures is the negative cache, it remembers failed renders for a short time.
+type failures struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]time.Time
+}
+
+func newFailures(ttl time.Duration) *failures {
+	return &failures{
+		ttl:     ttl,
+		entries: map[string]time.Time{},
+	}
+}
+
+func (f *failures) add(key string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	for k, until := range f.entries {
+		if now.After(until) {
+			delete(f.entries, k)
+		}
+	}
+	f.entries[key] = now.Add(f.ttl)
+}
+
+func (f *failures) remove(key string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.entries, key)
+}
+
+// failed reports whether the render of key failed less than the TTL ago.
+func (f *failures) failed(key string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	until, ok := f.entries[key]
+	return ok && time.Now().Before(until)
+}
+
+func hostOf(query string) string {
+	u, err := url.Parse(query)
+	if err != nil {
+		return query
+	}
+	return u.Host
+}
@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"errors"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_breaker(t *testing.T) {
+	b := newBreaker(BreakerConfig{Failures: 2, OpenFor: renderer.Duration(time.Hour)})
+	failed := errors.New("failed")
+
+	b.done("a.com", failed)
+	assert.True(t, b.allow("a.com"))
+	b.done("a.com", failed)
+	assert.False(t, b.allow("a.com"))
+	assert.True(t, b.allow("b.com"))
+
+	// half-open: a single probe once OpenFor elapsed
+	b.hosts["a.com"].openedAt = time.Now().Add(-2 * time.Hour)
+	assert.True(t, b.allow("a.com"))
+	assert.False(t, b.allow("a.com"))
+
+	b.done("a.com", failed)
+	assert.False(t, b.allow("a.com"))
+
+	b.hosts["a.com"].openedAt = time.Now().Add(-2 * time.Hour)
+	assert.True(t, b.allow("a.com"))
+	b.done("a.com", nil)
+	assert.True(t, b.allow("a.com"))
+	assert.True(t, b.allow("a.com"))
+}
+
+func Test_breaker_cancel(t *testing.T) {
+	b := newBreaker(BreakerConfig{Failures: 1, OpenFor: renderer.Duration(time.Hour)})
+	b.done("a.com", errors.New("failed"))
+	b.hosts["a.com"].openedAt = time.Now().Add(-2 * time.Hour)
+
+	// a probe refused by the rate limit leaves the circuit half-open, not open for good
+	assert.True(t, b.allow("a.com"))
+	b.cancel("a.com")
+	assert.True(t, b.allow("a.com"))
+	assert.False(t, b.allow("a.com"))
+
+	b.cancel("b.com")
+	assert.True(t, b.allow("b.com"))
+}
+
+func Test_failures(t *testing.T) {
+	f := newFailures(time.Hour)
+	f.add("key")
+	assert.True(t, f.failed("key"))
+	assert.False(t, f.failed("other"))
+	f.remove("key")
+	assert.False(t, f.failed("key"))
+}
@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"github.com/goprerender/prerender/pkg/renderer"
+	"time"
+)
+
+// Config holds the executor settings.
+type Config struct {
+	// FailureTTL is how long a failed render is remembered, the query fails fast meanwhile.
+	FailureTTL renderer.Duration `json:"failureTtl"`
+	Breaker    BreakerConfig     `json:"breaker"`
+	// Versions is the number of versions kept per page, 5 by default.
+	Versions int `json:"versions"`
+	// Codec compresses the cached pages: gzip (default), br or zstd.
+	Codec string `json:"codec"`
+}
+
+// BreakerConfig configures the circuit breaker of the origin hosts.
+type BreakerConfig struct {
+	// Failures in a row which open the circuit of a host.
+	Failures int `json:"failures"`
+	// OpenFor is how long the circuit stays open before a probe render is let through.
+	OpenFor renderer.Duration `json:"openFor"`
+}
+
+func (c Config) failureTTL() time.Duration {
+	if c.FailureTTL <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.FailureTTL)
+}
+
+func (c Config) codec() string {
+	if c.Codec == "" {
+		return "gzip"
+	}
+	return c.Codec
+}
+
+func (c Config) versions() int {
+	if c.Versions <= 0 {
+		return 5
+	}
+	return c.Versions
+}
+
+func (c BreakerConfig) failures() int {
+	if c.Failures <= 0 {
+		return 5
+	}
+	return c.Failures
+}
+
+func (c BreakerConfig) openFor() time.Duration {
+	if c.OpenFor <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.OpenFor)
+}
@@ -2,24 +2,49 @@ package executor
 
 import (
 	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/goprerender/prerender/internal/archive"
 	"github.com/goprerender/prerender/internal/cachers"
+	"github.com/goprerender/prerender/internal/cachers/history"
 	"github.com/goprerender/prerender/pkg/log"
 	"github.com/goprerender/prerender/pkg/renderer"
+	"github.com/goprerender/prerender/pkg/seo"
 	"github.com/goprerender/prerender/pkg/url"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+var errRedirect = errors.New("error: cached redirect, not a page")
+
 type Executor struct {
 	renderer *renderer.Renderer
 	pc       cachers.Сacher
+	codec    archive.Codec
+	history  *history.History
+	changed  uint64
+	same     uint64
+	breaker  *breaker
+	failures *failures
 	logger   log.Logger
 }
 
-func NewExecutor(renderer *renderer.Renderer, c cachers.Сacher, logger log.Logger) *Executor {
+func NewExecutor(renderer *renderer.Renderer, c cachers.Сacher, config Config, logger log.Logger) *Executor {
+	codec, err := archive.Lookup(config.codec())
+	if err != nil {
+		logger.Error(err, ", gzip is used")
+		codec = archive.Gzip
+	}
+
 	return &Executor{
 		renderer: renderer,
 		pc:       c,
+		codec:    codec,
+		history:  history.New(c, config.versions()),
+		breaker:  newBreaker(config.Breaker),
+		failures: newFailures(config.failureTTL()),
 		logger:   logger,
 	}
 }
@@ -28,38 +53,438 @@ func (e *Executor) GetPC() cachers.Сacher {
 	return e.pc
 }
 
-func (e *Executor) Execute(query string, force bool) (string, error) {
-	var res string
-
-	hostPath, err := url.SlashRemover(query, e.logger)
+// Execute returns the page of the query from the cache or renders it. acceptLanguage is the crawler
+// header, it selects the locale variant of hosts rendered in several locales. traffic selects
+// the rate limit budget of the host.
+// When the render fails the cached copy is returned if there is one. Failed renders are not
+// retried for Config.FailureTTL, and hosts failing repeatedly are not rendered at all for a while.
+// The result is returned with the errors too, without page, e.g. with the render of a redirect.
+func (e *Executor) Execute(query string, force bool, acceptLanguage string, traffic renderer.Traffic) (*Result, error) {
+	t, err := e.target(query, acceptLanguage)
 	if err != nil {
 
-		return hostPath, err
+		return &Result{Cache: CacheMiss}, err
 	}
 
-	key := fmt.Sprintf("%x", sha256.Sum256([]byte(hostPath)))
+	p, status, render, err := e.execute(query, t, force, traffic)
+	res := &Result{Page: p, Cache: status, render: render}
+	if err != nil {
+		return res, err
+	}
+	// what is stored next to the page is read only if the response needs it
+	if p.stored == nil {
+		p.stored = &stored{}
+	}
+	p.stored.e, p.stored.key = e, t.key
+	return res, nil
+}
+
+// execute returns the page with its cache status, and the render info of the renders
+// returning no page.
+func (e *Executor) execute(query string, t target, force bool, traffic renderer.Traffic) (*Page, CacheStatus, *renderInfo, error) {
 	//e.logger.Infof("hostPath: %s, query: %s", hostPath, query)
-	value, err := e.pc.Get(key)
-	if force || err != nil {
+	value, cacheErr := e.pc.Get(t.key)
+	if force || cacheErr != nil {
 		/*start:
 		if e.renderer.IsRestarting() {
 			time.Sleep(time.Second)
 			goto start
 		}*/
-		res, err = e.renderer.DoRender(query)
-		if err != nil {
-			return res, err
+		status := CacheMiss
+		if force {
+			status = CacheForced
+		}
+		if !force && e.failures.failed(t.key) {
+			return nil, status, nil, ErrRecentlyFailed
+		}
+		if !e.breaker.allow(hostOf(query)) {
+			if cacheErr == nil {
+				// stale copy while the origin is failing
+				p, err := e.cached(t.key, value)
+				return p, CacheStale, nil, err
+			}
+			return nil, status, nil, ErrCircuitOpen
 		}
 
-		//e.logger.Infof("html: %s", res)
+		p, diagnostics, err := e.render(query, t, renderer.Options{Locale: t.locale, Traffic: traffic})
+		var re *renderer.RedirectError
+		if err != nil && !errors.As(err, &re) && cacheErr == nil {
+			// the previous good copy is served instead, with the render info stored with it
+			p, err := e.cached(t.key, value)
+			return p, CacheStale, nil, err
+		}
+		if err != nil {
+			return nil, status, newRenderInfo(renderer.Metadata{}, diagnostics), err
+		}
+		return p, status, nil, nil
+	}
+	p, err := e.cached(t.key, value)
+	return p, CacheHit, nil, err
+}
 
-		htmlGzip := archive.GzipHtml(res, hostPath, "", e.logger)
-		err = e.pc.Put(key, htmlGzip)
+// cached returns the cached page, or the RedirectError of a cached redirect.
+func (e *Executor) cached(key string, value []byte) (*Page, error) {
+	if status, ok := cachedRedirect(value); ok {
+		location, err := archive.Read(value)
 		if err != nil {
-			e.logger.Warn("Can't store result in cache")
+			return nil, err
 		}
+		return nil, &renderer.RedirectError{Location: string(location), Status: status}
+	}
+	return cachedPage(value, e.codec, &stored{e: e, key: key})
+}
+
+// RenderHTML renders a submitted document, the result is not cached.
+func (e *Executor) RenderHTML(html, baseURL string, screenshot bool) ([]byte, error) {
+	return e.renderer.RenderHTML(html, baseURL, screenshot)
+}
+
+// RetryAfter returns how long a failed render is remembered, rendering the query again
+// fails fast meanwhile.
+func (e *Executor) RetryAfter() time.Duration {
+	return e.failures.ttl
+}
+
+// Endpoint returns the Chrome the renderer is connected to.
+func (e *Executor) Endpoint() string {
+	return e.renderer.Endpoint()
+}
+
+// QueueStats returns the render scheduler numbers per traffic class.
+func (e *Executor) QueueStats() []renderer.QueueStats {
+	return e.renderer.QueueStats()
+}
+
+// Locales returns the locale variants of the query, nil if its host doesn't vary by language.
+func (e *Executor) Locales(query string) []string {
+	return e.renderer.Locales(query)
+}
+
+// target is where a query is cached.
+type target struct {
+	hostPath string
+	locale   string
+	key      string
+}
+
+func (e *Executor) target(query, acceptLanguage string) (target, error) {
+	hostPath, err := url.SlashRemover(query, e.logger)
+	if err != nil {
+		return target{hostPath: hostPath}, err
+	}
+
+	t := target{
+		hostPath: hostPath,
+		locale:   e.renderer.Locale(query, acceptLanguage),
+	}
+	// pages without locale keep their plain key
+	t.key = cacheKey(hostPath)
+	if t.locale != "" {
+		t.key = cacheKey(hostPath + "#" + t.locale)
+	}
+	return t, nil
+}
+
+// render renders the query and caches the page, or the redirect, with its diagnostics.
+func (e *Executor) render(query string, t target, opts renderer.Options) (*Page, *renderer.Diagnostics, error) {
+	hostPath, key := t.hostPath, t.key
+
+	res, diagnostics, err := e.renderer.Render(query, opts)
+	e.storeDiagnostics(key, diagnostics)
+
+	var re *renderer.RedirectError
+	if errors.Is(err, renderer.ErrRateLimited) {
+		// the render never started, the origin did not fail
+		e.breaker.cancel(hostOf(query))
+		return nil, diagnostics, err
+	}
+	if err != nil && !errors.As(err, &re) {
+		e.failures.add(key)
+		e.breaker.done(hostOf(query), err)
 	} else {
-		res = archive.UnzipHtml(value, e.logger)
+		e.failures.remove(key)
+		e.breaker.done(hostOf(query), nil)
 	}
-	return res, nil
+
+	if errors.As(err, &re) {
+		// the redirect is cached instead of the page, the comment marks it
+		comment := fmt.Sprintf("%s %d", redirectComment, re.Status)
+		value, packErr := archive.Pack(e.codec, []byte(re.Location), hostPath, comment)
+		if packErr != nil {
+			e.logger.Error(packErr)
+			return nil, diagnostics, err
+		}
+		e.store(key, hostPath, value)
+		return nil, diagnostics, err
+	}
+	var ve *renderer.ValidationError
+	if errors.As(err, &ve) {
+		e.logger.Warnf("Page not cached: %s, url: %s", ve.Reason, hostPath)
+		return nil, diagnostics, err
+	}
+	if err != nil {
+		return nil, diagnostics, err
+	}
+
+	//e.logger.Infof("html: %s", res)
+
+	meta, err := seo.Parse(res.HTML, res.FinalURL)
+	if err != nil {
+		return nil, diagnostics, err
+	}
+	meta.Robots.Add(header(res.Headers, "X-Robots-Tag"))
+
+	p, err := newPage(res.HTML, hostPath, meta.Robots.NoIndex, e.codec)
+	if err != nil {
+		return nil, diagnostics, err
+	}
+	info := newRenderInfo(res.Metadata, diagnostics)
+	p.stored = &stored{info: info, infoRead: true}
+	e.store(key, hostPath, p.packed())
+	e.keep(key+metadataKey, info, "metadata")
+	e.keep(key+seoKey, meta, "SEO metadata")
+	return p, diagnostics, nil
+}
+
+// store caches a render as a new version of the page if its content changed.
+func (e *Executor) store(key, hostPath string, value []byte) {
+	changed, err := e.history.Put(key, value)
+	if err != nil {
+		e.logger.Warn("Can't store result in cache")
+		return
+	}
+	if changed {
+		atomic.AddUint64(&e.changed, 1)
+		e.logger.Debugf("Content changed: %s", hostPath)
+		return
+	}
+	atomic.AddUint64(&e.same, 1)
+	e.logger.Debugf("Content unchanged: %s", hostPath)
+}
+
+// Changes returns how many renders changed the content of their page and how many did not.
+func (e *Executor) Changes() (changed, unchanged uint64) {
+	return atomic.LoadUint64(&e.changed), atomic.LoadUint64(&e.same)
+}
+
+// Versions returns the kept versions of the page of the query, newest first.
+func (e *Executor) Versions(query, acceptLanguage string) ([]history.Version, error) {
+	t, err := e.target(query, acceptLanguage)
+	if err != nil {
+		return nil, err
+	}
+	return e.history.Versions(t.key)
+}
+
+// Version returns the HTML, or the location of a redirect, of a version of the page.
+func (e *Executor) Version(query, acceptLanguage string, id int) (string, error) {
+	t, err := e.target(query, acceptLanguage)
+	if err != nil {
+		return "", err
+	}
+	value, err := e.history.Get(t.key, id)
+	if err != nil {
+		return "", err
+	}
+	content, err := archive.Read(value)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Diff returns the line diff between two versions of the page.
+func (e *Executor) Diff(query, acceptLanguage string, from, to int) (string, error) {
+	a, err := e.Version(query, acceptLanguage, from)
+	if err != nil {
+		return "", err
+	}
+	b, err := e.Version(query, acceptLanguage, to)
+	if err != nil {
+		return "", err
+	}
+	return history.Diff(a, b), nil
+}
+
+// Restore caches a previous version of the page again.
+func (e *Executor) Restore(query, acceptLanguage string, id int) error {
+	t, err := e.target(query, acceptLanguage)
+	if err != nil {
+		return err
+	}
+	value, err := e.history.Get(t.key, id)
+	if err != nil {
+		return err
+	}
+	if _, err := e.history.Put(t.key, value); err != nil {
+		return err
+	}
+	e.keepRestored(query, t.key, value)
+	return nil
+}
+
+// keepRestored replaces what is stored next to a restored page, it describes the replaced render.
+// The render details of the restored version are not known, its metadata is read from its HTML.
+func (e *Executor) keepRestored(query, key string, value []byte) {
+	info, meta := &renderInfo{}, &seo.Metadata{}
+	if m, noIndex, err := e.parse(query, value); err == nil {
+		meta = m
+		meta.Robots.NoIndex = meta.Robots.NoIndex || noIndex
+		info.Metadata = renderer.Metadata{FinalURL: query, Title: m.Title, Description: m.Description, Canonical: m.Canonical}
+	}
+	e.keep(key+metadataKey, info, "metadata")
+	e.keep(key+seoKey, meta, "SEO metadata")
+}
+
+// parse returns the SEO metadata of a cached page and whether it is flagged noindex.
+func (e *Executor) parse(query string, value []byte) (*seo.Metadata, bool, error) {
+	if _, ok := cachedRedirect(value); ok {
+		return nil, false, errRedirect
+	}
+	p, err := cachedPage(value, e.codec, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	html, err := p.HTML()
+	if err != nil {
+		return nil, false, err
+	}
+	meta, err := seo.Parse(html, query)
+	return meta, p.NoIndex(), err
+}
+
+// RecordHAR renders the query again while recording its network activity and keeps the HAR.
+func (e *Executor) RecordHAR(query, acceptLanguage string) (*renderer.HAR, error) {
+	t, err := e.target(query, acceptLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	_, diagnostics, err := e.render(query, t, renderer.Options{HAR: true, Locale: t.locale})
+	if diagnostics == nil || diagnostics.HAR == nil {
+		return nil, err
+	}
+
+	b, jsonErr := json.Marshal(diagnostics.HAR)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	value, err := archive.Pack(e.codec, b, t.hostPath, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := e.pc.Put(t.key+harKey, value); err != nil {
+		e.logger.Warn("Can't store HAR in cache")
+	}
+	return diagnostics.HAR, nil
+}
+
+// HAR returns the JSON of the last HAR recorded for the query.
+func (e *Executor) HAR(query, acceptLanguage string) ([]byte, error) {
+	t, err := e.target(query, acceptLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := e.pc.Get(t.key + harKey)
+	if err != nil {
+		return nil, err
+	}
+	return archive.Read(value)
+}
+
+const (
+	redirectComment = "redirect"
+	diagnosticsKey  = ":diagnostics"
+	metadataKey     = ":metadata"
+	seoKey          = ":seo"
+	harKey          = ":har"
+)
+
+func cacheKey(hostPath string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(hostPath)))
+}
+
+// Diagnostics returns the diagnostics of the last render of the query.
+func (e *Executor) Diagnostics(query, acceptLanguage string) (*renderer.Diagnostics, error) {
+	t, err := e.target(query, acceptLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.diagnostics(t.key)
+}
+
+func (e *Executor) diagnostics(key string) (*renderer.Diagnostics, error) {
+	var d renderer.Diagnostics
+	if err := e.load(key+diagnosticsKey, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// storeDiagnostics keeps the diagnostics next to the cached page.
+func (e *Executor) storeDiagnostics(key string, d *renderer.Diagnostics) {
+	if d == nil {
+		return
+	}
+	e.keep(key+diagnosticsKey, d, "diagnostics")
+}
+
+// SEO returns the SEO metadata of the cached page of the query.
+func (e *Executor) SEO(query, acceptLanguage string) (*seo.Metadata, error) {
+	t, err := e.target(query, acceptLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	var m seo.Metadata
+	if err := e.load(t.key+seoKey, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// load reads the JSON value of key into v.
+func (e *Executor) load(key string, v interface{}) error {
+	value, err := e.pc.Get(key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(value, v)
+}
+
+// keep stores v as JSON at key, what names it in the logs.
+func (e *Executor) keep(key string, v interface{}, what string) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		e.logger.Error(err)
+		return
+	}
+	if err := e.pc.Put(key, b); err != nil {
+		e.logger.Warnf("Can't store %s in cache", what)
+	}
+}
+
+// cachedRedirect reports whether the cached value is a redirect and returns its status.
+func cachedRedirect(value []byte) (int, bool) {
+	var status int
+	s, err := archive.Unpack(value)
+	if err != nil {
+		return 0, false
+	}
+	if _, err := fmt.Sscanf(s.Comment, redirectComment+" %d", &status); err != nil {
+		return 0, false
+	}
+	return status, true
+}
+
+// header returns the value of the response header name, header names are case insensitive.
+func header(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
 }
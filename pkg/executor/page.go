@@ -0,0 +1,233 @@
+package executor
+
+import (
+	"github.com/goprerender/prerender/internal/archive"
+	"github.com/goprerender/prerender/internal/cachers/history"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"github.com/goprerender/prerender/pkg/seo"
+	"strings"
+	"time"
+)
+
+const (
+	// pageComment marks the pages cached ready to be served: scripts are stripped and
+	// not indexable pages are flagged, so the compressed data can be sent as it is.
+	pageComment    = "page"
+	noIndexComment = pageComment + " noindex"
+)
+
+// Page is a page as it is cached, compressed.
+type Page struct {
+	value    []byte
+	snapshot *archive.Snapshot
+	html     *string
+	etag     string
+	noIndex  bool
+	// stored is what is kept next to the page, nil if it is not known.
+	stored *stored
+}
+
+// renderInfo is kept next to a page: the metadata of its render and how the render went.
+// It is stored only with the page, so it always describes the render of the cached page.
+type renderInfo struct {
+	renderer.Metadata
+	RenderedAt time.Time `json:"renderedAt"`
+	// Duration is in seconds, like Diagnostics.Duration.
+	Duration float64 `json:"duration"`
+	Endpoint string  `json:"endpoint"`
+}
+
+// newRenderInfo returns the render info of a render from its metadata and diagnostics, if known.
+func newRenderInfo(metadata renderer.Metadata, diagnostics *renderer.Diagnostics) *renderInfo {
+	info := &renderInfo{Metadata: metadata}
+	if diagnostics != nil {
+		info.RenderedAt = diagnostics.RenderedAt
+		info.Duration = diagnostics.Duration
+		info.Endpoint = diagnostics.Endpoint
+	}
+	return info
+}
+
+// stored reads what is kept next to a cached page on first use, most responses need only
+// part of it.
+type stored struct {
+	e           *Executor
+	key         string
+	version     *history.Version
+	versionRead bool
+	info        *renderInfo
+	infoRead    bool
+}
+
+// Version returns the current version of the page in the history, nil if it is not known.
+func (s *stored) Version() *history.Version {
+	if s == nil {
+		return nil
+	}
+	if !s.versionRead && s.e != nil {
+		if versions, err := s.e.history.Versions(s.key); err == nil && len(versions) > 0 {
+			s.version = &versions[0]
+		}
+	}
+	s.versionRead = true
+	return s.version
+}
+
+// Info returns how the page was rendered, nil if it is not known.
+func (s *stored) Info() *renderInfo {
+	if s == nil {
+		return nil
+	}
+	if !s.infoRead && s.e != nil {
+		var info renderInfo
+		if err := s.e.load(s.key+metadataKey, &info); err == nil {
+			s.info = &info
+		}
+	}
+	s.infoRead = true
+	return s.info
+}
+
+// newPage prepares a rendered page for the cache, noIndex flags the pages robots must not index.
+func newPage(res, hostPath string, noIndex bool, codec archive.Codec) (*Page, error) {
+	res = stripAllTags(res, "<script>", "</script>")
+
+	comment := pageComment
+	if noIndex {
+		comment = noIndexComment
+	}
+
+	b, err := archive.Pack(codec, []byte(res), hostPath, comment)
+	if err != nil {
+		return nil, err
+	}
+	s, err := archive.Unpack(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{
+		value:    b,
+		snapshot: s,
+		html:     &res,
+		noIndex:  noIndex,
+	}, nil
+}
+
+// cachedPage reads a page from the cache with what is stored next to it. Pages cached
+// before they were processed are processed again, with the X-Robots-Tag header of their
+// stored metadata.
+func cachedPage(value []byte, codec archive.Codec, st *stored) (*Page, error) {
+	s, err := archive.Unpack(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.Comment {
+	case pageComment:
+		return &Page{value: value, snapshot: s, stored: st}, nil
+	case noIndexComment:
+		return &Page{value: value, snapshot: s, noIndex: true, stored: st}, nil
+	}
+
+	content, err := s.Content()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := seo.Parse(string(content), "")
+	if err != nil {
+		return nil, err
+	}
+	if info := st.Info(); info != nil {
+		meta.Robots.Add(header(info.Headers, "X-Robots-Tag"))
+	}
+	p, err := newPage(string(content), s.Name, meta.Robots.NoIndex, codec)
+	if err != nil {
+		return nil, err
+	}
+	// the page was cached when it was rendered, not now
+	p.snapshot.Created = s.Created
+	p.stored = st
+	return p, nil
+}
+
+// Encoding returns the HTTP content coding of the compressed page.
+func (p *Page) Encoding() string {
+	return p.snapshot.Codec.Name()
+}
+
+// Compressed returns the compressed HTML.
+func (p *Page) Compressed() []byte {
+	return p.snapshot.Data
+}
+
+// packed returns the page as it is stored.
+func (p *Page) packed() []byte {
+	return p.value
+}
+
+// HTML returns the HTML, it is decompressed on the first call.
+func (p *Page) HTML() (string, error) {
+	if p.html == nil {
+		content, err := p.snapshot.Content()
+		if err != nil {
+			return "", err
+		}
+		html := string(content)
+		p.html = &html
+	}
+	return *p.html, nil
+}
+
+// ETag returns a weak entity tag of the page content, the same for every encoding. It is the
+// content hash of the page version, computed on the first call.
+func (p *Page) ETag() string {
+	if p.etag == "" {
+		hash := history.ContentHash(p.value)
+		if len(hash) > 32 {
+			hash = hash[:32]
+		}
+		p.etag = `W/"` + hash + `"`
+	}
+	return p.etag
+}
+
+// Modified returns when the content of the page last changed, zero if it is unknown.
+// Pages without history were last changed when they were cached.
+func (p *Page) Modified() time.Time {
+	if v := p.stored.Version(); v != nil {
+		return v.CreatedAt
+	}
+	return p.snapshot.Created
+}
+
+// Metadata returns the metadata of the render of the page, nil if it is unknown.
+func (p *Page) Metadata() *renderer.Metadata {
+	if info := p.stored.Info(); info != nil {
+		return &info.Metadata
+	}
+	return nil
+}
+
+// NoIndex reports whether the page asks robots not to index it.
+func (p *Page) NoIndex() bool {
+	return p.noIndex
+}
+
+func stripAllTags(str, start, end string) string {
+
+	s := strings.Index(str, start[:len(start)-1])
+	if s == -1 {
+		return str
+	}
+
+	e := strings.Index(str, end)
+	if e == -1 {
+		return str
+	}
+	e += len(end)
+
+	str = str[0:s] + str[e:]
+
+	return stripAllTags(str, start, end)
+}
@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"github.com/bluele/gcache"
+	"github.com/goprerender/prerender/internal/archive"
+	"github.com/goprerender/prerender/internal/cachers/history"
+	"github.com/goprerender/prerender/internal/cachers/inmemory"
+	"github.com/goprerender/prerender/pkg/renderer"
+	"github.com/goprerender/prerender/pkg/seo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPage_Modified(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	p, err := newPage("<html><body>Hello</body></html>", "example.com/", false, archive.Gzip)
+	assert.NoError(t, err)
+
+	cached, err := cachedPage(p.packed(), archive.Gzip, nil)
+	assert.NoError(t, err)
+	assert.True(t, cached.Modified().After(start))
+	assert.Equal(t, p.snapshot.Created, cached.Modified())
+
+	changed := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cached.stored = &stored{version: &history.Version{CreatedAt: changed}, versionRead: true}
+	assert.Equal(t, changed, cached.Modified())
+
+	// legacy pages are processed again but keep their time
+	legacy, err := archive.Pack(archive.Gzip, []byte("<html><script>x</script></html>"), "example.com/", "")
+	assert.NoError(t, err)
+	s, _ := archive.Unpack(legacy)
+	time.Sleep(2 * time.Millisecond)
+	cached, err = cachedPage(legacy, archive.Gzip, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, s.Created, cached.Modified())
+	assert.False(t, cached.NoIndex())
+}
+
+func TestCachedPage_robotsHeader(t *testing.T) {
+	legacy, err := archive.Pack(archive.Gzip, []byte("<html><body>Hello</body></html>"), "example.com/", "")
+	assert.NoError(t, err)
+
+	// the header of a legacy page is only in its stored metadata
+	info := &renderInfo{Metadata: renderer.Metadata{Headers: map[string]string{"x-robots-tag": "noindex"}}}
+	cached, err := cachedPage(legacy, archive.Gzip, &stored{info: info, infoRead: true})
+	assert.NoError(t, err)
+	assert.True(t, cached.NoIndex())
+
+	again, err := cachedPage(cached.packed(), archive.Gzip, nil)
+	assert.NoError(t, err)
+	assert.True(t, again.NoIndex())
+}
+
+func TestExecutor_keepRestored(t *testing.T) {
+	e := &Executor{pc: inmemory.New(gcache.New(10).LRU().Build()), codec: archive.Gzip}
+	e.keep("key"+metadataKey, &renderInfo{Metadata: renderer.Metadata{Title: "Replaced"}, Endpoint: "local:1"}, "metadata")
+
+	p, err := newPage("<html><head><title>Restored</title></head><body>Hello</body></html>", "example.com/", true, archive.Gzip)
+	assert.NoError(t, err)
+	e.keepRestored("https://example.com/", "key", p.packed())
+
+	cached := &Page{stored: &stored{e: e, key: "key"}}
+	assert.Equal(t, "Restored", cached.Metadata().Title)
+	assert.Equal(t, "", cached.stored.Info().Endpoint)
+	var meta seo.Metadata
+	assert.NoError(t, e.load("key"+seoKey, &meta))
+	assert.Equal(t, "Restored", meta.Title)
+	assert.True(t, meta.Robots.NoIndex)
+
+	// a restored redirect has no page metadata
+	redirect, err := archive.Pack(archive.Gzip, []byte("https://example.com/new"), "example.com/", redirectComment+" 301")
+	assert.NoError(t, err)
+	e.keepRestored("https://example.com/", "key", redirect)
+	assert.NoError(t, e.load("key"+seoKey, &meta))
+	assert.Equal(t, "", meta.Title)
+}
+
+func TestPage_ETag(t *testing.T) {
+	p, err := newPage("<html><body>Hello</body></html>", "example.com/", false, archive.Gzip)
+	assert.NoError(t, err)
+	hash := history.ContentHash(p.packed())
+	assert.Equal(t, `W/"`+hash[:32]+`"`, p.ETag())
+
+	// the same without history, or with another codec
+	cached, err := cachedPage(p.packed(), archive.Gzip, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, p.ETag(), cached.ETag())
+	other, err := newPage("<html><body>Hello</body></html>", "example.com/", false, archive.Brotli)
+	assert.NoError(t, err)
+	assert.Equal(t, p.ETag(), other.ETag())
+}
@@ -0,0 +1,73 @@
+package executor
+
+import (
+	"time"
+)
+
+// CacheStatus tells how a page was served.
+type CacheStatus string
+
+const (
+	// CacheHit is a page served from the cache.
+	CacheHit CacheStatus = "HIT"
+	// CacheMiss is a page rendered because it was not cached.
+	CacheMiss CacheStatus = "MISS"
+	// CacheStale is a cached page served because the render failed or its host is failing.
+	CacheStale CacheStatus = "STALE"
+	// CacheForced is a page rendered again on request.
+	CacheForced CacheStatus = "FORCED"
+	// CacheBypass is a page rendered without the cache.
+	CacheBypass CacheStatus = "BYPASS"
+)
+
+// Result is a page with how it was served. Page is nil when there is no page to serve, e.g. a redirect.
+type Result struct {
+	Page  *Page
+	Cache CacheStatus
+	// render describes the render of a result without page.
+	render *renderInfo
+}
+
+// info returns the render info of the result, nil if it is not known.
+func (r *Result) info() *renderInfo {
+	if r.render != nil {
+		return r.render
+	}
+	if r.Page != nil {
+		return r.Page.stored.Info()
+	}
+	return nil
+}
+
+// RenderedAt returns when the page was rendered, zero if it is not known.
+func (r *Result) RenderedAt() time.Time {
+	if info := r.info(); info != nil {
+		return info.RenderedAt
+	}
+	return time.Time{}
+}
+
+// RenderTime returns how long the render of the page took, zero if it is not known.
+func (r *Result) RenderTime() time.Duration {
+	if info := r.info(); info != nil {
+		return time.Duration(info.Duration * float64(time.Second))
+	}
+	return 0
+}
+
+// Endpoint returns the Chrome which rendered the page, "" if it is not known.
+func (r *Result) Endpoint() string {
+	if info := r.info(); info != nil {
+		return info.Endpoint
+	}
+	return ""
+}
+
+// Age returns how long ago the page was rendered, zero if it is not known.
+func (r *Result) Age() time.Duration {
+	renderedAt := r.RenderedAt()
+	if renderedAt.IsZero() {
+		return 0
+	}
+	return time.Since(renderedAt)
+}
@@ -0,0 +1,131 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/publicsuffix"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultBlockedURLs are blocked for every host, BlockConfig.URLs are added to them.
+var defaultBlockedURLs = []string{"google-analytics.com", "mc.yandex.ru", "maps.googleapis.com", "googletagmanager.com"}
+
+// BlockConfig sets which requests of a page are not loaded during the render.
+type BlockConfig struct {
+	// URLs are Network.setBlockedURLs patterns blocked besides the default ones, "*" is a wildcard.
+	URLs []string `json:"urls"`
+	// ResourceTypes are blocked with request interception: "Image", "Media", "Font", "Stylesheet"...
+	ResourceTypes []string `json:"resourceTypes"`
+	// FirstPartyOnly blocks requests to other sites than the page one and AllowDomains.
+	FirstPartyOnly bool     `json:"firstPartyOnly"`
+	AllowDomains   []string `json:"allowDomains"`
+}
+
+// blocker applies a BlockConfig to one render and counts the blocked requests.
+type blocker struct {
+	config    BlockConfig
+	urls      []string
+	types     map[string]bool
+	site      string
+	mutex     sync.Mutex
+	counts    map[string]int
+	intercept bool
+}
+
+func newBlocker(config *BlockConfig, requestURL string) *blocker {
+	b := &blocker{
+		types:  map[string]bool{},
+		counts: map[string]int{},
+	}
+	if config != nil {
+		b.config = *config
+	}
+	b.urls = append(append([]string{}, defaultBlockedURLs...), b.config.URLs...)
+	for _, t := range b.config.ResourceTypes {
+		b.types[strings.ToLower(t)] = true
+	}
+	if u, err := url.Parse(requestURL); err == nil {
+		b.site = siteOf(u.Hostname())
+	}
+	b.intercept = len(b.types) > 0 || b.config.FirstPartyOnly
+	return b
+}
+
+// Listen counts the requests blocked by URL, the intercepted ones are counted by the interceptor.
+func (b *blocker) Listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*network.EventLoadingFailed); ok && ev.BlockedReason == network.BlockedReasonInspector {
+			b.count("url")
+		}
+	})
+}
+
+// Actions returns the actions that set the URL blocking up in the tab.
+func (b *blocker) Actions() []chromedp.Action {
+	return []chromedp.Action{
+		network.SetBlockedURLS(b.urls),
+	}
+}
+
+// blocked returns why the request is blocked or an empty string.
+func (b *blocker) blocked(requestURL string, t network.ResourceType) string {
+	if b.types[strings.ToLower(string(t))] {
+		return string(t)
+	}
+	if !b.config.FirstPartyOnly {
+		return ""
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	host := u.Hostname()
+	if siteOf(host) == b.site {
+		return ""
+	}
+	for _, domain := range b.config.AllowDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return ""
+		}
+	}
+	return "third-party"
+}
+
+func (b *blocker) count(reason string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.counts[reason]++
+}
+
+// Summary returns the blocked request counts, e.g. "12 (Image: 9, url: 3)".
+func (b *blocker) Summary() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	total := 0
+	parts := make([]string, 0, len(b.counts))
+	for reason, n := range b.counts {
+		total += n
+		parts = append(parts, fmt.Sprintf("%s: %d", reason, n))
+	}
+	sort.Strings(parts)
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(parts, ", "))
+}
+
+// siteOf returns the registrable domain of host, e.g. "example.co.uk" for "www.example.co.uk".
+func siteOf(host string) string {
+	site, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return site
+}
@@ -0,0 +1,50 @@
+package renderer
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfig_hostConfig(t *testing.T) {
+	images := &BlockConfig{ResourceTypes: []string{"image"}}
+	all := &BlockConfig{FirstPartyOnly: true}
+	c := Config{Hosts: map[string]HostConfig{
+		"www.example.com": {Block: images},
+		"*.example.org":   {Block: all},
+		"*":               {},
+	}}
+
+	assert.Equal(t, images, c.hostConfig("https://www.example.com/page").Block)
+	assert.Equal(t, all, c.hostConfig("https://shop.example.org/").Block)
+	assert.Equal(t, all, c.hostConfig("https://example.org/").Block)
+	assert.Nil(t, c.hostConfig("https://example.net/").Block)
+}
+
+func Test_blocker_blocked(t *testing.T) {
+	b := newBlocker(&BlockConfig{
+		ResourceTypes:  []string{"image", "Font"},
+		FirstPartyOnly: true,
+		AllowDomains:   []string{"cdn.net"},
+	}, "https://www.example.co.uk/page")
+
+	assert.Equal(t, "Image", b.blocked("https://www.example.co.uk/a.png", network.ResourceTypeImage))
+	assert.Equal(t, "Font", b.blocked("https://www.example.co.uk/a.woff", network.ResourceTypeFont))
+	assert.Empty(t, b.blocked("https://static.example.co.uk/app.js", network.ResourceTypeScript))
+	assert.Empty(t, b.blocked("https://img.cdn.net/app.js", network.ResourceTypeScript))
+	assert.Empty(t, b.blocked("data:text/plain,hi", network.ResourceTypeScript))
+	assert.Equal(t, "third-party", b.blocked("https://tracker.com/t.js", network.ResourceTypeScript))
+
+	assert.Equal(t, defaultBlockedURLs, newBlocker(nil, "https://example.com").urls)
+	assert.False(t, newBlocker(nil, "https://example.com").intercept)
+}
+
+func Test_blocker_urls(t *testing.T) {
+	// a config with resource types only keeps the default URLs, never sends a null list
+	b := newBlocker(&BlockConfig{ResourceTypes: []string{"image"}}, "https://example.com")
+	assert.Equal(t, defaultBlockedURLs, b.urls)
+	assert.True(t, b.intercept)
+
+	b = newBlocker(&BlockConfig{URLs: []string{"*.hotjar.com"}}, "https://example.com")
+	assert.Equal(t, append(append([]string{}, defaultBlockedURLs...), "*.hotjar.com"), b.urls)
+}
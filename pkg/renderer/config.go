@@ -0,0 +1,96 @@
+package renderer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Mode selects where the renderer gets its browser from.
+type Mode string
+
+const (
+	// ModeAuto tries the docker container first, then a remote Chrome and falls back to a local one.
+	ModeAuto Mode = ""
+	// ModeDocker uses the headless-shell docker container.
+	ModeDocker Mode = "docker"
+	// ModeRemote connects to an already running Chrome.
+	ModeRemote Mode = "remote"
+	// ModeLocal launches and supervises a local Chrome/Chromium process.
+	ModeLocal Mode = "local"
+)
+
+const defaultRemoteURL = "http://localhost:9222"
+
+// Config holds the renderer settings.
+type Config struct {
+	Mode Mode `json:"mode"`
+	// RemoteURL is the DevTools HTTP endpoint of a remote or docker Chrome.
+	RemoteURL string          `json:"remoteUrl"`
+	Local     LocalConfig     `json:"local"`
+	Recycle   RecycleConfig   `json:"recycle"`
+	Scheduler SchedulerConfig `json:"scheduler"`
+	// Hosts are per-host render settings, see HostConfig.
+	Hosts map[string]HostConfig `json:"hosts"`
+	// Origins map public origins to the backends Chrome really fetches from,
+	// e.g. {"https://www.example.com": "http://internal-web:8080"}.
+	Origins map[string]string `json:"origins"`
+}
+
+// LocalConfig holds the settings of a locally launched browser.
+type LocalConfig struct {
+	// ExecPath is the Chrome/Chromium binary, searched in the usual locations when empty.
+	ExecPath    string `json:"execPath"`
+	Headless    *bool  `json:"headless"`
+	NoSandbox   bool   `json:"noSandbox"`
+	ProxyServer string `json:"proxyServer"`
+	UserDataDir string `json:"userDataDir"`
+	// Flags are extra command line flags, e.g. {"disable-gpu": true, "lang": "en-US"}.
+	Flags map[string]interface{} `json:"flags"`
+}
+
+// RecycleConfig sets when the browser is drained and restarted. Zero values disable a limit.
+// A remote browser is never recycled, it may be shared with other processes.
+type RecycleConfig struct {
+	MaxRenders int      `json:"maxRenders"`
+	MaxAge     Duration `json:"maxAge"`
+	// MaxMemoryMB is the memory of the browser process tree (local) or the container (docker).
+	MaxMemoryMB int `json:"maxMemoryMb"`
+	// MaxHeapMB is the JS heap of a rendered page reported by Performance.getMetrics.
+	MaxHeapMB int `json:"maxHeapMb"`
+	// CheckInterval is how often age and memory are checked, one minute by default.
+	CheckInterval Duration `json:"checkInterval"`
+	// SharedContainer keeps the headless-shell container from being restarted, for
+	// containers other processes render in.
+	SharedContainer bool `json:"sharedContainer"`
+}
+
+// Duration is a time.Duration read from JSON strings like "90s" or "12h".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (c Config) remoteURL() string {
+	if c.RemoteURL == "" {
+		return defaultRemoteURL
+	}
+	return c.RemoteURL
+}
+
+func (c LocalConfig) headless() bool {
+	return c.Headless == nil || *c.Headless
+}
@@ -0,0 +1,223 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxDiagnosticEntries = 50
+	maxDiagnosticText    = 500
+)
+
+// Diagnostics is a compact record of what happened in the page during a render.
+type Diagnostics struct {
+	URL        string    `json:"url"`
+	RenderedAt time.Time `json:"renderedAt"`
+	Duration   float64   `json:"duration"`
+	// Endpoint is the Chrome which rendered the page, see Renderer.Endpoint.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Status is the HTTP status of the document.
+	Status         int64            `json:"status,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	Console        []ConsoleMessage `json:"console,omitempty"`
+	Exceptions     []string         `json:"exceptions,omitempty"`
+	FailedRequests []FailedRequest  `json:"failedRequests,omitempty"`
+	// HAR is the network activity, recorded if asked by Options.
+	HAR *HAR `json:"-"`
+}
+
+// ConsoleMessage is a console API call of the page.
+type ConsoleMessage struct {
+	Level string `json:"level"`
+	Text  string `json:"text"`
+}
+
+// FailedRequest is a request which was blocked, did not load or got an HTTP error.
+type FailedRequest struct {
+	URL     string `json:"url"`
+	Type    string `json:"type"`
+	Status  int64  `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Blocked bool   `json:"blocked,omitempty"`
+}
+
+// collector gathers the Diagnostics of one render from the CDP events.
+type collector struct {
+	mutex    sync.Mutex
+	d        Diagnostics
+	requests map[network.RequestID]string
+	headers  map[string]string
+	har      *harRecorder
+	// frame is the main frame, its document requests form the navigation
+	frame     string
+	navURL    string
+	navStatus int64
+}
+
+func newCollector(requestURL, endpoint string, recordHAR bool) *collector {
+	c := &collector{
+		d:        Diagnostics{URL: requestURL, Endpoint: endpoint},
+		requests: map[network.RequestID]string{},
+	}
+	if recordHAR {
+		c.har = newHARRecorder(requestURL)
+	}
+	return c
+}
+
+// Listen collects console messages, exceptions and failed requests of the tab.
+func (c *collector) Listen(ctx context.Context) {
+	if c.har != nil {
+		c.har.Listen(ctx)
+	}
+	chromedp.ListenTarget(ctx, c.handle)
+}
+
+func (c *collector) handle(ev interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		if len(c.d.Console) < maxDiagnosticEntries {
+			c.d.Console = append(c.d.Console, ConsoleMessage{Level: string(ev.Type), Text: consoleText(ev.Args)})
+		}
+	case *runtime.EventExceptionThrown:
+		if len(c.d.Exceptions) < maxDiagnosticEntries {
+			c.d.Exceptions = append(c.d.Exceptions, exceptionText(ev.ExceptionDetails))
+		}
+	case *network.EventRequestWillBeSent:
+		c.requests[ev.RequestID] = ev.Request.URL
+		if ev.Type == network.ResourceTypeDocument && c.d.Status == 0 {
+			c.navigate(ev)
+		}
+	case *network.EventResponseReceived:
+		// redirects don't get a response event, the first document is the page
+		if ev.Type == network.ResourceTypeDocument && c.d.Status == 0 {
+			c.d.Status = ev.Response.Status
+			c.headers = make(map[string]string, len(ev.Response.Headers))
+			for name, value := range ev.Response.Headers {
+				c.headers[name] = fmt.Sprint(value)
+			}
+		}
+		if ev.Response.Status >= 400 {
+			c.fail(FailedRequest{URL: ev.Response.URL, Type: string(ev.Type), Status: ev.Response.Status})
+		}
+	case *network.EventLoadingFailed:
+		if ev.Canceled {
+			return
+		}
+		c.fail(FailedRequest{
+			URL:     c.requests[ev.RequestID],
+			Type:    string(ev.Type),
+			Error:   ev.ErrorText,
+			Blocked: ev.BlockedReason != "" || strings.Contains(ev.ErrorText, "BLOCKED_BY_CLIENT"),
+		})
+	}
+}
+
+func (c *collector) fail(r FailedRequest) {
+	if len(c.d.FailedRequests) < maxDiagnosticEntries {
+		r.URL = truncate(r.URL)
+		c.d.FailedRequests = append(c.d.FailedRequests, r)
+	}
+}
+
+// status returns the HTTP status of the document, 0 if no response was received yet.
+func (c *collector) status() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.d.Status
+}
+
+// navigate follows the document requests of the main frame until its response, the ones
+// sent with a redirect response are its HTTP redirects.
+func (c *collector) navigate(ev *network.EventRequestWillBeSent) {
+	if c.frame == "" {
+		c.frame = string(ev.FrameID)
+	}
+	if string(ev.FrameID) != c.frame {
+		return
+	}
+	c.navURL = ev.Request.URL
+	if ev.RedirectResponse != nil && c.navStatus == 0 {
+		c.navStatus = ev.RedirectResponse.Status
+	}
+}
+
+// navigation returns the URL the document was loaded from and the status of the first HTTP
+// redirect to it, 0 without redirects.
+func (c *collector) navigation() (string, int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.navURL, c.navStatus
+}
+
+// response returns the HTTP status and the response headers of the document.
+func (c *collector) response() (int64, map[string]string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.d.Status, c.headers
+}
+
+// Result returns the collected diagnostics, nil if the render never reached the browser.
+func (c *collector) Result(start time.Time, err error) *Diagnostics {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	d := c.d
+	d.RenderedAt = start
+	d.Duration = time.Since(start).Seconds()
+	if err != nil {
+		d.Error = err.Error()
+	}
+	if c.har != nil {
+		d.HAR = c.har.Result()
+	}
+	return &d
+}
+
+func consoleText(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		var str string
+		switch {
+		case arg.Type == runtime.TypeString && json.Unmarshal(arg.Value, &str) == nil:
+			parts = append(parts, str)
+		case arg.Description != "":
+			parts = append(parts, arg.Description)
+		default:
+			parts = append(parts, string(arg.Value))
+		}
+	}
+	return truncate(strings.Join(parts, " "))
+}
+
+func exceptionText(details *runtime.ExceptionDetails) string {
+	if details == nil {
+		return ""
+	}
+	text := details.Text
+	if details.Exception != nil && details.Exception.Description != "" {
+		text = details.Exception.Description
+	}
+	return truncate(text)
+}
+
+func truncate(s string) string {
+	if len(s) > maxDiagnosticText {
+		return s[:maxDiagnosticText] + "..."
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package renderer
+
+import (
+	"errors"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_collector(t *testing.T) {
+	c := newCollector("https://example.com/old", "local", false)
+
+	c.handle(&runtime.EventConsoleAPICalled{
+		Type: runtime.APITypeLog,
+		Args: []*runtime.RemoteObject{
+			{Type: runtime.TypeString, Value: []byte(`"loaded"`)},
+			{Type: runtime.TypeObject, Description: "Object"},
+			{Type: runtime.TypeNumber, Value: []byte(`42`)},
+		},
+	})
+	c.handle(&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{
+		Text:      "Uncaught",
+		Exception: &runtime.RemoteObject{Description: "TypeError: x is undefined"},
+	}})
+
+	c.handle(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		FrameID:   "main",
+		Request:   &network.Request{URL: "https://example.com/old"},
+		Type:      network.ResourceTypeDocument,
+	})
+	c.handle(&network.EventRequestWillBeSent{
+		RequestID:        "1",
+		FrameID:          "main",
+		Request:          &network.Request{URL: "https://example.com/new"},
+		RedirectResponse: &network.Response{Status: 301},
+		Type:             network.ResourceTypeDocument,
+	})
+	c.handle(&network.EventRequestWillBeSent{
+		RequestID: "2",
+		FrameID:   "ad",
+		Request:   &network.Request{URL: "https://ads.example.net/frame"},
+		Type:      network.ResourceTypeDocument,
+	})
+	assert.Equal(t, int64(0), c.status())
+
+	c.handle(&network.EventResponseReceived{
+		RequestID: "1",
+		Type:      network.ResourceTypeDocument,
+		Response: &network.Response{
+			URL:     "https://example.com/new",
+			Status:  200,
+			Headers: network.Headers{"Content-Type": "text/html"},
+		},
+	})
+	c.handle(&network.EventResponseReceived{
+		RequestID: "2",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{URL: "https://ads.example.net/frame", Status: 404},
+	})
+
+	c.handle(&network.EventRequestWillBeSent{RequestID: "3", Request: &network.Request{URL: "https://example.com/app.js"}})
+	c.handle(&network.EventLoadingFailed{RequestID: "3", Type: network.ResourceTypeScript, ErrorText: "net::ERR_BLOCKED_BY_CLIENT"})
+	c.handle(&network.EventRequestWillBeSent{RequestID: "4", Request: &network.Request{URL: "https://example.com/poll"}})
+	c.handle(&network.EventLoadingFailed{RequestID: "4", Type: network.ResourceTypeXHR, ErrorText: "net::ERR_ABORTED", Canceled: true})
+
+	navURL, redirect := c.navigation()
+	assert.Equal(t, "https://example.com/new", navURL)
+	assert.Equal(t, int64(301), redirect)
+
+	status, headers := c.response()
+	assert.Equal(t, int64(200), status)
+	assert.Equal(t, map[string]string{"Content-Type": "text/html"}, headers)
+
+	start := time.Now()
+	d := c.Result(start, errors.New("error: timeout"))
+	assert.Equal(t, "https://example.com/old", d.URL)
+	assert.Equal(t, "local", d.Endpoint)
+	assert.Equal(t, start, d.RenderedAt)
+	assert.Equal(t, int64(200), d.Status)
+	assert.Equal(t, "error: timeout", d.Error)
+	assert.Equal(t, []ConsoleMessage{{Level: "log", Text: "loaded Object 42"}}, d.Console)
+	assert.Equal(t, []string{"TypeError: x is undefined"}, d.Exceptions)
+	assert.Equal(t, []FailedRequest{
+		{URL: "https://ads.example.net/frame", Type: "Document", Status: 404},
+		{URL: "https://example.com/app.js", Type: "Script", Error: "net::ERR_BLOCKED_BY_CLIENT", Blocked: true},
+	}, d.FailedRequests)
+	assert.Nil(t, d.HAR)
+}
+
+func Test_collector_limits(t *testing.T) {
+	c := newCollector("https://example.com/", "", true)
+	long := strings.Repeat("x", maxDiagnosticText+10)
+
+	for i := 0; i < maxDiagnosticEntries+5; i++ {
+		c.handle(&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{Text: long}})
+		c.handle(&network.EventResponseReceived{Response: &network.Response{URL: long, Status: 500}})
+	}
+
+	d := c.Result(time.Now(), nil)
+	assert.Len(t, d.Exceptions, maxDiagnosticEntries)
+	assert.Len(t, d.FailedRequests, maxDiagnosticEntries)
+	assert.Equal(t, maxDiagnosticText+3, len(d.Exceptions[0]))
+	assert.True(t, strings.HasSuffix(d.FailedRequests[0].URL, "..."))
+	assert.Empty(t, d.Error)
+	assert.NotNil(t, d.HAR)
+
+	var none *collector
+	assert.Nil(t, none.Result(time.Now(), nil))
+}
@@ -0,0 +1,316 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HAR is an HTTP Archive 1.2 document.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HARPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings are in milliseconds since the start of the render, -1 when the event did not fire.
+type HARPageTimings struct {
+	OnLoad float64 `json:"onLoad"`
+}
+
+type HAREntry struct {
+	Pageref         string      `json:"pageref"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+	BlockedReason   string      `json:"_blockedReason,omitempty"`
+
+	start float64
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARTimings are in milliseconds, -1 when not applicable.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+const harPageID = "page_1"
+
+// harRecorder builds a HAR from the Network events of one render.
+type harRecorder struct {
+	mutex    sync.Mutex
+	started  time.Time
+	url      string
+	pending  map[network.RequestID]*HAREntry
+	finished []HAREntry
+	// onLoad is the time to the load event of the page in milliseconds, -1 until it fires.
+	onLoad float64
+}
+
+func newHARRecorder(requestURL string) *harRecorder {
+	return &harRecorder{
+		started: time.Now(),
+		url:     requestURL,
+		pending: map[network.RequestID]*HAREntry{},
+		onLoad:  -1,
+	}
+}
+
+// Listen records the network activity of the tab.
+func (h *harRecorder) Listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, h.handle)
+}
+
+func (h *harRecorder) handle(ev interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if e, ok := h.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+			h.response(e, ev.RedirectResponse)
+			h.finish(ev.RequestID, ev.Timestamp)
+		}
+		h.pending[ev.RequestID] = h.request(ev)
+	case *network.EventResponseReceived:
+		if e, ok := h.pending[ev.RequestID]; ok {
+			h.response(e, ev.Response)
+		}
+	case *network.EventLoadingFinished:
+		if e, ok := h.pending[ev.RequestID]; ok {
+			e.Response.BodySize = int(ev.EncodedDataLength)
+			h.finish(ev.RequestID, ev.Timestamp)
+		}
+	case *network.EventLoadingFailed:
+		if e, ok := h.pending[ev.RequestID]; ok {
+			e.Error = ev.ErrorText
+			e.BlockedReason = string(ev.BlockedReason)
+			h.finish(ev.RequestID, ev.Timestamp)
+		}
+	case *page.EventLoadEventFired:
+		if h.onLoad < 0 {
+			h.onLoad = float64(time.Since(h.started).Milliseconds())
+		}
+	}
+}
+
+func (h *harRecorder) request(ev *network.EventRequestWillBeSent) *HAREntry {
+	e := &HAREntry{
+		Pageref:      harPageID,
+		ResourceType: string(ev.Type),
+		Request: HARRequest{
+			Method:      ev.Request.Method,
+			URL:         ev.Request.URL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(ev.Request.Headers),
+			QueryString: []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(ev.Request.PostData),
+		},
+		Response: HARResponse{
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	if ev.WallTime != nil {
+		e.StartedDateTime = ev.WallTime.Time()
+	}
+	if ev.Timestamp != nil {
+		e.start = monotonicSeconds(ev.Timestamp)
+	}
+	if u, err := url.Parse(ev.Request.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, value := range values {
+				e.Request.QueryString = append(e.Request.QueryString, HARNameValue{Name: name, Value: value})
+			}
+		}
+	}
+	if ev.Request.PostData != "" {
+		e.Request.PostData = &HARPostData{
+			MimeType: fmt.Sprint(ev.Request.Headers["Content-Type"]),
+			Text:     ev.Request.PostData,
+		}
+	}
+	return e
+}
+
+func (h *harRecorder) response(e *HAREntry, r *network.Response) {
+	e.Response.Status = r.Status
+	e.Response.StatusText = r.StatusText
+	e.Response.Headers = harHeaders(r.Headers)
+	e.Response.Content = HARContent{Size: int(r.EncodedDataLength), MimeType: r.MimeType}
+	e.ServerIPAddress = r.RemoteIPAddress
+	if r.Protocol != "" {
+		e.Request.HTTPVersion = r.Protocol
+		e.Response.HTTPVersion = r.Protocol
+	}
+	for _, header := range e.Response.Headers {
+		if strings.EqualFold(header.Name, "Location") {
+			e.Response.RedirectURL = header.Value
+		}
+	}
+
+	if t := r.Timing; t != nil {
+		e.Timings.Blocked = positive(t.DNSStart, t.ConnectStart, t.SendStart)
+		e.Timings.DNS = span(t.DNSStart, t.DNSEnd)
+		e.Timings.Connect = span(t.ConnectStart, t.ConnectEnd)
+		e.Timings.SSL = span(t.SslStart, t.SslEnd)
+		e.Timings.Send = t.SendEnd - t.SendStart
+		e.Timings.Wait = t.ReceiveHeadersEnd - t.SendEnd
+	}
+}
+
+func (h *harRecorder) finish(id network.RequestID, ts *cdp.MonotonicTime) {
+	e := h.pending[id]
+	delete(h.pending, id)
+
+	if ts != nil && e.start > 0 {
+		e.Time = (monotonicSeconds(ts) - e.start) * 1000
+		spent := e.Timings.Send + e.Timings.Wait
+		for _, t := range []float64{e.Timings.Blocked, e.Timings.DNS, e.Timings.Connect} {
+			if t > 0 {
+				spent += t
+			}
+		}
+		if receive := e.Time - spent; receive > 0 {
+			e.Timings.Receive = receive
+		}
+	}
+	h.finished = append(h.finished, *e)
+}
+
+// Result returns the HAR of the render, unfinished requests are included as they are.
+func (h *harRecorder) Result() *HAR {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	entries := append([]HAREntry{}, h.finished...)
+	for _, e := range h.pending {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "prerender", Version: "1.0"},
+		Pages: []HARPage{{
+			StartedDateTime: h.started,
+			ID:              harPageID,
+			Title:           h.url,
+			PageTimings:     HARPageTimings{OnLoad: h.onLoad},
+		}},
+		Entries: entries,
+	}}
+}
+
+func harHeaders(headers network.Headers) []HARNameValue {
+	res := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		res = append(res, HARNameValue{Name: name, Value: fmt.Sprint(value)})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func monotonicSeconds(t *cdp.MonotonicTime) float64 {
+	return float64(t.Time().UnixNano()) / float64(time.Second)
+}
+
+// span returns end-start in milliseconds, -1 if the phase did not happen.
+func span(start, end float64) float64 {
+	if start < 0 || end < 0 {
+		return -1
+	}
+	return end - start
+}
+
+// positive returns the first non negative value, the time spent before that phase.
+func positive(values ...float64) float64 {
+	for _, v := range values {
+		if v >= 0 {
+			return v
+		}
+	}
+	return -1
+}
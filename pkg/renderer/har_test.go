@@ -0,0 +1,79 @@
+package renderer
+
+import (
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_harRecorder(t *testing.T) {
+	h := newHARRecorder("https://example.com/")
+	start := time.Now()
+	at := func(ms int) *cdp.MonotonicTime {
+		ts := cdp.MonotonicTime(start.Add(time.Duration(ms) * time.Millisecond))
+		return &ts
+	}
+	wall := func(ms int) *cdp.TimeSinceEpoch {
+		ts := cdp.TimeSinceEpoch(start.Add(time.Duration(ms) * time.Millisecond))
+		return &ts
+	}
+
+	h.handle(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request:   &network.Request{Method: "GET", URL: "https://example.com/?q=1"},
+		Timestamp: at(0),
+		WallTime:  wall(0),
+		Type:      network.ResourceTypeDocument,
+	})
+	h.handle(&network.EventResponseReceived{
+		RequestID: "1",
+		Response: &network.Response{
+			Status:   200,
+			Headers:  network.Headers{"Content-Type": "text/html"},
+			MimeType: "text/html",
+			Protocol: "h2",
+		},
+	})
+	h.handle(&network.EventLoadingFinished{RequestID: "1", Timestamp: at(120), EncodedDataLength: 512})
+	h.handle(&network.EventRequestWillBeSent{
+		RequestID: "2",
+		Request:   &network.Request{Method: "GET", URL: "https://example.com/app.js"},
+		Timestamp: at(130),
+		WallTime:  wall(130),
+		Type:      network.ResourceTypeScript,
+	})
+	h.handle(&network.EventLoadingFailed{RequestID: "2", Timestamp: at(140), ErrorText: "net::ERR_BLOCKED_BY_CLIENT"})
+	h.handle(&network.EventRequestWillBeSent{
+		RequestID: "3",
+		Request:   &network.Request{Method: "GET", URL: "https://example.com/pending.png"},
+		Timestamp: at(150),
+		WallTime:  wall(150),
+	})
+
+	assert.Equal(t, float64(-1), h.Result().Log.Pages[0].PageTimings.OnLoad)
+
+	h.handle(&page.EventLoadEventFired{Timestamp: at(200)})
+	onLoad := h.Result().Log.Pages[0].PageTimings.OnLoad
+	time.Sleep(5 * time.Millisecond)
+	h.handle(&page.EventLoadEventFired{Timestamp: at(300)})
+
+	har := h.Result()
+	assert.True(t, onLoad >= 0)
+	assert.Equal(t, onLoad, har.Log.Pages[0].PageTimings.OnLoad)
+	assert.Len(t, har.Log.Entries, 3)
+
+	doc := har.Log.Entries[0]
+	assert.Equal(t, "https://example.com/?q=1", doc.Request.URL)
+	assert.Equal(t, []HARNameValue{{Name: "q", Value: "1"}}, doc.Request.QueryString)
+	assert.Equal(t, int64(200), doc.Response.Status)
+	assert.Equal(t, "h2", doc.Response.HTTPVersion)
+	assert.Equal(t, 512, doc.Response.BodySize)
+	assert.InDelta(t, 120, doc.Time, 0.001)
+
+	assert.Equal(t, "net::ERR_BLOCKED_BY_CLIENT", har.Log.Entries[1].Error)
+	assert.Equal(t, "https://example.com/pending.png", har.Log.Entries[2].Request.URL)
+	assert.Equal(t, float64(0), har.Log.Entries[2].Time)
+}
@@ -0,0 +1,78 @@
+package renderer
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HostConfig holds the render settings of one target host.
+type HostConfig struct {
+	Block *BlockConfig `json:"block"`
+	// Headers are sent with the requests of the page to its own site, not to third parties.
+	Headers   map[string]string `json:"headers"`
+	BasicAuth *BasicAuth        `json:"basicAuth"`
+	Cookies   []Cookie          `json:"cookies"`
+	// BeforeLoad is injected into the page with Page.addScriptToEvaluateOnNewDocument.
+	BeforeLoad string `json:"beforeLoad"`
+	// AfterLoad are executed in order once the page is loaded.
+	AfterLoad []PageAction `json:"afterLoad"`
+	// ShadowDOM keeps open shadow roots in the captured HTML as declarative shadow DOM.
+	ShadowDOM bool `json:"shadowDom"`
+	// RedirectStatus is used for client side redirects, 301 or 302 (default).
+	RedirectStatus int `json:"redirectStatus"`
+	// Locale renders the host always in this locale, e.g. "de-DE".
+	Locale string `json:"locale"`
+	// Locales are the supported locales matched against the crawler Accept-Language,
+	// each one is cached separately.
+	Locales []string `json:"locales"`
+	// Timezone is an IANA timezone, e.g. "Europe/Berlin".
+	Timezone string `json:"timezone"`
+	// Validate rejects error pages and half loaded pages instead of caching them.
+	Validate *ValidateConfig `json:"validate"`
+	// RateLimit protects the origin from too many renders.
+	RateLimit *RateLimit `json:"rateLimit"`
+}
+
+// Prepare readies a request made outside the browser to a target host, e.g. a link check: it adds
+// the headers and the credentials of the host and waits for its rate limit as Background traffic, at
+// most until the deadline of the request context. release must be called once the request ends.
+func (r *Renderer) Prepare(req *http.Request) (release func(), err error) {
+	host := r.config.hostConfig(req.URL.String())
+	for name, value := range host.Headers {
+		req.Header.Set(name, value)
+	}
+	if host.BasicAuth != nil {
+		req.SetBasicAuth(host.BasicAuth.Username, host.BasicAuth.Password)
+	}
+	return r.limiter.wait(req.Context(), req.URL.String(), Background, host.RateLimit)
+}
+
+// hostConfig returns the settings for the host of requestURL. Config.Hosts keys are host names,
+// "*.example.com" for the domain and its subdomains and "*" for any other host.
+func (c Config) hostConfig(requestURL string) HostConfig {
+	if len(c.Hosts) == 0 {
+		return HostConfig{}
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return c.Hosts["*"]
+	}
+	host := u.Hostname()
+
+	if hc, ok := c.Hosts[host]; ok {
+		return hc
+	}
+	for domain := host; domain != ""; {
+		if hc, ok := c.Hosts["*."+domain]; ok {
+			return hc
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return c.Hosts["*"]
+}
@@ -0,0 +1,84 @@
+package renderer
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// RenderHTML executes the scripts of a submitted document and returns the resulting HTML,
+// or a PNG screenshot of it. When baseURL is set the document is served at that URL,
+// so relative links, cookies and storage behave as on the real page. The document is rendered
+// like a page of the host of baseURL: with its blocking, headers, credentials and rate limit.
+func (r *Renderer) RenderHTML(html, baseURL string, screenshot bool) ([]byte, error) {
+	if baseURL != "" {
+		release, err := r.waitLimit(baseURL, Interactive)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	r.scheduler.Acquire(Interactive)
+	defer r.scheduler.Release(Interactive)
+
+	r.recycler.Acquire()
+	defer func() {
+		if reason := r.recycler.Release(0); reason != "" {
+			go r.recycle(reason)
+		}
+	}()
+
+	newTabCtx, cancel := chromedp.NewContext(r.allocator())
+	defer cancel()
+
+	ctx, cancel := context.WithTimeout(newTabCtx, renderTimeout)
+	defer cancel()
+
+	host := r.config.hostConfig(baseURL)
+
+	b := newBlocker(host.Block, baseURL)
+	b.Listen(ctx)
+
+	i := newInterceptor(b, r.config.Origins, host.Headers, host.BasicAuth, baseURL)
+	if baseURL != "" {
+		i.serve(html)
+	}
+	i.Listen(ctx)
+
+	actions := append(b.Actions(), i.Actions()...)
+	actions = append(actions, network.SetExtraHTTPHeaders(network.Headers{"X-Prerender-Next": "1"}))
+	if baseURL != "" {
+		actions = append(actions, chromedp.Navigate(baseURL))
+	} else {
+		actions = append(actions,
+			chromedp.Navigate("about:blank"),
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				tree, err := page.GetFrameTree().Do(ctx)
+				if err != nil {
+					return err
+				}
+				return page.SetDocumentContent(tree.Frame.ID, html).Do(ctx)
+			}),
+		)
+	}
+	actions = append(actions, afterLoad(host.AfterLoad)...)
+
+	var res []byte
+	if screenshot {
+		actions = append(actions, chromedp.FullScreenshot(&res, 100))
+	} else {
+		var outer string
+		actions = append(actions, chromedp.OuterHTML("html", &outer, chromedp.ByQuery), chromedp.ActionFunc(func(context.Context) error {
+			res = []byte(outer)
+			return nil
+		}))
+	}
+
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		r.logger.Error("ChromeDP HTML render error: ", err)
+		return nil, err
+	}
+	return res, nil
+}
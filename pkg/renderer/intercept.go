@@ -0,0 +1,211 @@
+package renderer
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// BasicAuth are the credentials sent when the page site asks for HTTP authentication.
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Cookie is set in the browser before the page is loaded.
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Domain and Path default to the ones of the rendered URL.
+	Domain   string `json:"domain"`
+	Path     string `json:"path"`
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"httpOnly"`
+}
+
+// interceptor handles the paused requests of one render: it blocks them, rewrites
+// their origin, adds the host headers or lets them through, and answers the authentication challenges.
+// A submitted document is served as the answer of the first document request.
+type interceptor struct {
+	blocker  *blocker
+	origins  map[string]string
+	headers  map[string]string
+	auth     *BasicAuth
+	site     string
+	document string
+	served   sync.Once
+}
+
+func newInterceptor(b *blocker, origins map[string]string, headers map[string]string, auth *BasicAuth, requestURL string) *interceptor {
+	i := &interceptor{
+		blocker: b,
+		origins: map[string]string{},
+		headers: headers,
+		auth:    auth,
+	}
+	for public, internal := range origins {
+		i.origins[strings.TrimRight(public, "/")] = strings.TrimRight(internal, "/")
+	}
+	if u, err := url.Parse(requestURL); err == nil {
+		i.site = siteOf(u.Hostname())
+	}
+	return i
+}
+
+func (i *interceptor) enabled() bool {
+	return i.blocker.intercept || len(i.origins) > 0 || len(i.headers) > 0 || i.auth != nil || i.document != ""
+}
+
+// serve answers the first document request with html instead of the site.
+func (i *interceptor) serve(html string) {
+	i.document = html
+}
+
+// ownSite reports whether requestURL belongs to the page site.
+func (i *interceptor) ownSite(requestURL string) bool {
+	u, err := url.Parse(requestURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && siteOf(u.Hostname()) == i.site
+}
+
+// Listen answers the Fetch domain events of the tab.
+func (i *interceptor) Listen(ctx context.Context) {
+	if !i.enabled() {
+		return
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() {
+				ctx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+				_ = i.requestPaused(ev).Do(ctx)
+			}()
+		case *fetch.EventAuthRequired:
+			go func() {
+				ctx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+				_ = i.authRequired(ev).Do(ctx)
+			}()
+		}
+	})
+}
+
+// Actions returns the actions that turn the interception on in the tab.
+func (i *interceptor) Actions() []chromedp.Action {
+	if !i.enabled() {
+		return nil
+	}
+	return []chromedp.Action{
+		fetch.Enable().WithHandleAuthRequests(i.auth != nil),
+	}
+}
+
+func (i *interceptor) requestPaused(ev *fetch.EventRequestPaused) chromedp.Action {
+	if i.document != "" && ev.ResourceType == network.ResourceTypeDocument {
+		served := false
+		i.served.Do(func() { served = true })
+		if served {
+			return fetch.FulfillRequest(ev.RequestID, 200).
+				WithResponseHeaders([]*fetch.HeaderEntry{{Name: "Content-Type", Value: "text/html; charset=utf-8"}}).
+				WithBody(base64.StdEncoding.EncodeToString([]byte(i.document)))
+		}
+	}
+	if reason := i.blocker.blocked(ev.Request.URL, ev.ResourceType); reason != "" {
+		i.blocker.count(reason)
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+	}
+
+	target, rewrite := rewriteOrigin(i.origins, ev.Request.URL)
+	// the host headers may hold credentials, they are only sent to the page site
+	own := len(i.headers) > 0 && i.ownSite(ev.Request.URL)
+	if !rewrite && !own {
+		return fetch.ContinueRequest(ev.RequestID)
+	}
+
+	headers := make([]*fetch.HeaderEntry, 0, len(ev.Request.Headers)+len(i.headers)+2)
+	for name, value := range ev.Request.Headers {
+		if own && i.hasHeader(name) {
+			continue
+		}
+		headers = append(headers, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	if own {
+		for name, value := range i.headers {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+		}
+	}
+	if !rewrite {
+		return fetch.ContinueRequest(ev.RequestID).WithHeaders(headers)
+	}
+
+	// the page keeps seeing the public URL, the backend learns it from the forwarded headers
+	if u, err := url.Parse(ev.Request.URL); err == nil {
+		headers = append(headers,
+			&fetch.HeaderEntry{Name: "X-Forwarded-Host", Value: u.Host},
+			&fetch.HeaderEntry{Name: "X-Forwarded-Proto", Value: u.Scheme},
+		)
+	}
+	return fetch.ContinueRequest(ev.RequestID).WithURL(target).WithHeaders(headers)
+}
+
+// hasHeader reports whether name is one of the host headers, header names are case insensitive.
+func (i *interceptor) hasHeader(name string) bool {
+	for h := range i.headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *interceptor) authRequired(ev *fetch.EventAuthRequired) chromedp.Action {
+	response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+
+	// credentials are only given to the page site
+	if u, err := url.Parse(ev.AuthChallenge.Origin); err == nil && i.auth != nil && siteOf(u.Hostname()) == i.site {
+		response = &fetch.AuthChallengeResponse{
+			Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+			Username: i.auth.Username,
+			Password: i.auth.Password,
+		}
+	}
+	return fetch.ContinueWithAuth(ev.RequestID, response)
+}
+
+// rewriteOrigin maps a public URL to its internal origin, origins are keyed by "scheme://host[:port]".
+func rewriteOrigin(origins map[string]string, requestURL string) (string, bool) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", false
+	}
+	internal, ok := origins[u.Scheme+"://"+u.Host]
+	if !ok {
+		return "", false
+	}
+	return internal + u.RequestURI(), true
+}
+
+// cookieParams returns the cookies to set for requestURL.
+func cookieParams(cookies []Cookie, requestURL string) []*network.CookieParam {
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		p := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+		}
+		if p.Domain == "" {
+			p.URL = requestURL
+		}
+		params = append(params, p)
+	}
+	return params
+}
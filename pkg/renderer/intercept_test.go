@@ -0,0 +1,50 @@
+package renderer
+
+import (
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_rewriteOrigin(t *testing.T) {
+	i := newInterceptor(newBlocker(nil, ""), map[string]string{
+		"https://www.example.com/": "http://internal-web:8080/",
+	}, nil, nil, "https://www.example.com/")
+
+	u, ok := rewriteOrigin(i.origins, "https://www.example.com/page?a=1")
+	assert.True(t, ok)
+	assert.Equal(t, "http://internal-web:8080/page?a=1", u)
+
+	u, ok = rewriteOrigin(i.origins, "https://www.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "http://internal-web:8080/", u)
+
+	_, ok = rewriteOrigin(i.origins, "http://www.example.com/page")
+	assert.False(t, ok)
+	assert.True(t, i.enabled())
+}
+
+func Test_interceptor_ownSite(t *testing.T) {
+	i := newInterceptor(newBlocker(nil, ""), nil, map[string]string{"Authorization": "Bearer secret"}, nil, "https://www.example.com/")
+
+	assert.True(t, i.enabled())
+	assert.True(t, i.ownSite("https://static.example.com/app.js"))
+	assert.False(t, i.ownSite("https://www.google-analytics.com/collect"))
+	assert.False(t, i.ownSite("data:text/plain,hi"))
+	assert.True(t, i.hasHeader("authorization"))
+}
+
+func Test_interceptor_serve(t *testing.T) {
+	i := newInterceptor(newBlocker(nil, ""), nil, nil, nil, "https://www.example.com/")
+	assert.False(t, i.enabled())
+	i.serve("<html></html>")
+	assert.True(t, i.enabled())
+
+	// the submitted document answers the first document request only
+	script := &fetch.EventRequestPaused{RequestID: "1", ResourceType: network.ResourceTypeScript, Request: &network.Request{URL: "https://www.example.com/app.js"}}
+	assert.IsType(t, &fetch.ContinueRequestParams{}, i.requestPaused(script))
+	document := &fetch.EventRequestPaused{RequestID: "2", ResourceType: network.ResourceTypeDocument, Request: &network.Request{URL: "https://www.example.com/"}}
+	assert.IsType(t, &fetch.FulfillRequestParams{}, i.requestPaused(document))
+	assert.IsType(t, &fetch.ContinueRequestParams{}, i.requestPaused(document))
+}
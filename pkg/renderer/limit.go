@@ -0,0 +1,180 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Traffic is the class of a render, by priority. The renders other than Interactive also
+// use the background part of the rate limit of their host.
+type Traffic int
+
+const (
+	// Interactive renders answer a live crawler request.
+	Interactive Traffic = iota
+	// Recache renders refresh a page on demand, e.g. from a webhook.
+	Recache
+	// Background renders refresh the cache on schedule, e.g. from the sitemaps.
+	Background
+
+	trafficClasses = 3
+)
+
+func (t Traffic) String() string {
+	switch t {
+	case Interactive:
+		return "interactive"
+	case Recache:
+		return "recache"
+	case Background:
+		return "background"
+	}
+	return "unknown"
+}
+
+// RateLimit limits the renders of a host running at once and started per second, zero values
+// are unlimited. Every render of the host takes from the same budget, Background limits how much
+// of it the non interactive renders may use.
+type RateLimit struct {
+	Concurrency int     `json:"concurrency"`
+	RPS         float64 `json:"rps"`
+	Background  Budget  `json:"background"`
+}
+
+// Budget limits renders running at once and started per second, zero values are unlimited.
+type Budget struct {
+	Concurrency int     `json:"concurrency"`
+	RPS         float64 `json:"rps"`
+}
+
+func (b Budget) unlimited() bool {
+	return b.Concurrency <= 0 && b.RPS <= 0
+}
+
+// ErrRateLimited is returned when a render would wait for the rate limit of its host
+// longer than the render timeout.
+var ErrRateLimited = errors.New("error: host rate limit exceeded")
+
+// limiter holds the budgets in use per host.
+type limiter struct {
+	mutex   sync.Mutex
+	buckets map[bucketKey]*bucket
+}
+
+type bucketKey struct {
+	host       string
+	background bool
+}
+
+type bucket struct {
+	mutex    sync.Mutex
+	slots    chan struct{}
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: map[bucketKey]*bucket{}}
+}
+
+// wait blocks until a render of requestURL fits in the budget of its host, release must be called
+// when it ends. It returns ErrRateLimited instead of waiting past the deadline of ctx.
+func (l *limiter) wait(ctx context.Context, requestURL string, traffic Traffic, limit *RateLimit) (release func(), err error) {
+	if limit == nil {
+		return func() {}, nil
+	}
+
+	var host string
+	if u, err := url.Parse(requestURL); err == nil {
+		host = u.Hostname()
+	}
+
+	var releases []func()
+	release = func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	budgets := []bucketKey{{host: host}}
+	if traffic != Interactive {
+		// the background part is taken first, so it never holds a slot of the host
+		budgets = []bucketKey{{host: host, background: true}, {host: host}}
+	}
+	for _, key := range budgets {
+		budget := Budget{Concurrency: limit.Concurrency, RPS: limit.RPS}
+		if key.background {
+			budget = limit.Background
+		}
+		if budget.unlimited() {
+			continue
+		}
+		r, err := l.bucket(key, budget).take(ctx)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+	return release, nil
+}
+
+// take waits for a slot and the start time of the next render. The start is only reserved when it
+// is before the deadline of ctx, so waiting renders never push it further.
+func (b *bucket) take(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	if b.slots != nil {
+		select {
+		case b.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ErrRateLimited
+		}
+		release = func() { <-b.slots }
+	}
+	if b.interval <= 0 {
+		return release, nil
+	}
+
+	b.mutex.Lock()
+	start := b.next
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	if deadline, ok := ctx.Deadline(); ok && start.After(deadline) {
+		b.mutex.Unlock()
+		release()
+		return nil, ErrRateLimited
+	}
+	b.next = start.Add(b.interval)
+	b.mutex.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ErrRateLimited
+	}
+}
+
+func (l *limiter) bucket(key bucketKey, budget Budget) *bucket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{}
+		if budget.Concurrency > 0 {
+			b.slots = make(chan struct{}, budget.Concurrency)
+		}
+		if budget.RPS > 0 {
+			b.interval = time.Duration(float64(time.Second) / budget.RPS)
+		}
+		l.buckets[key] = b
+	}
+	return b
+}
@@ -0,0 +1,123 @@
+package renderer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func wait(t *testing.T, l *limiter, requestURL string, traffic Traffic, limit *RateLimit) {
+	release, err := l.wait(context.Background(), requestURL, traffic, limit)
+	assert.NoError(t, err)
+	release()
+}
+
+func Test_limiter_wait(t *testing.T) {
+	l := newLimiter()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		wait(t, l, "https://a.com/", Background, &RateLimit{RPS: 50})
+	}
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+
+	// other hosts have their own budget
+	start = time.Now()
+	wait(t, l, "https://b.com/", Background, &RateLimit{RPS: 1})
+	wait(t, l, "https://e.com/", Interactive, nil)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+
+	var running, max int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(traffic Traffic) {
+			defer wg.Done()
+			release, err := l.wait(context.Background(), "https://c.com/", traffic, &RateLimit{Concurrency: 2})
+			assert.NoError(t, err)
+			defer release()
+			n := atomic.AddInt32(&running, 1)
+			for m := atomic.LoadInt32(&max); n > m && !atomic.CompareAndSwapInt32(&max, m, n); m = atomic.LoadInt32(&max) {
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}(Traffic(i % trafficClasses))
+	}
+	wg.Wait()
+	// interactive and background renders share the budget of the host
+	assert.Equal(t, int32(2), max)
+}
+
+func Test_limiter_background(t *testing.T) {
+	l := newLimiter()
+	limit := &RateLimit{Concurrency: 2, Background: Budget{Concurrency: 1}}
+
+	release, err := l.wait(context.Background(), "https://a.com/", Background, limit)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.wait(ctx, "https://a.com/", Recache, limit)
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// the interactive renders still have the rest of the host budget
+	wait(t, l, "https://a.com/", Interactive, limit)
+	release()
+	wait(t, l, "https://a.com/", Recache, limit)
+}
+
+func Test_limiter_deadline(t *testing.T) {
+	l := newLimiter()
+	limit := &RateLimit{RPS: 2}
+
+	wait(t, l, "https://a.com/", Interactive, limit)
+
+	// the next start is 500ms away, after the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := l.wait(ctx, "https://a.com/", Interactive, limit)
+		assert.ErrorIs(t, err, ErrRateLimited)
+	}
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+
+	// the refused renders did not reserve anything
+	start = time.Now()
+	wait(t, l, "https://a.com/", Interactive, limit)
+	assert.True(t, time.Since(start) < 600*time.Millisecond)
+}
+
+func TestRenderer_Prepare(t *testing.T) {
+	limit := &RateLimit{Concurrency: 1}
+	r := &Renderer{limiter: newLimiter(), config: Config{Hosts: map[string]HostConfig{
+		"a.com": {Headers: map[string]string{"X-Token": "t"}, BasicAuth: &BasicAuth{Username: "u", Password: "p"}, RateLimit: limit},
+	}}}
+
+	req, _ := http.NewRequest(http.MethodHead, "https://a.com/page", nil)
+	release, err := r.Prepare(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "t", req.Header.Get("X-Token"))
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "u:p", username+":"+password)
+
+	// the request holds the slot of the host, the renders of the host wait for it
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = r.limiter.wait(ctx, "https://a.com/other", Interactive, limit)
+	assert.Equal(t, ErrRateLimited, err)
+	release()
+	wait(t, r.limiter, "https://a.com/other", Interactive, limit)
+
+	req, _ = http.NewRequest(http.MethodHead, "https://b.com/", nil)
+	release, err = r.Prepare(req)
+	assert.NoError(t, err)
+	release()
+	_, _, ok = req.BasicAuth()
+	assert.False(t, ok)
+}
@@ -0,0 +1,216 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/chromedp"
+	"github.com/goprerender/prerender/pkg/log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	ErrChromeNotFound = errors.New("error: Chrome/Chromium executable not found")
+	ErrChromeStopped  = errors.New("error: local Chrome stopped")
+)
+
+const maxRestartDelay = 30 * time.Second
+
+// localBrowser launches a Chrome process with chromedp.ExecAllocator and restarts it when it dies.
+type localBrowser struct {
+	config        LocalConfig
+	logger        log.Logger
+	mutex         sync.Mutex
+	allocCancel   context.CancelFunc
+	browserCancel context.CancelFunc
+	pid           int
+	stopped       bool
+}
+
+func newLocalBrowser(config LocalConfig, logger log.Logger) *localBrowser {
+	return &localBrowser{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Start launches the browser and returns the browser context new tabs are created from.
+func (b *localBrowser) Start() (context.Context, error) {
+	execPath, err := findChrome(b.config.ExecPath)
+	if err != nil {
+		return nil, err
+	}
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), b.options(execPath)...)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+
+	// an empty run starts the browser process
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, err
+	}
+
+	pid := 0
+	if p := chromedp.FromContext(browserCtx).Browser.Process(); p != nil {
+		pid = p.Pid
+	}
+
+	if !b.started(allocCancel, browserCancel, pid) {
+		return nil, ErrChromeStopped
+	}
+
+	b.logger.Infof("Local Chrome %s started, pid: %d", execPath, pid)
+
+	return browserCtx, nil
+}
+
+// started records a launched browser. A browser launched after Stop is closed at once, and
+// started returns false.
+func (b *localBrowser) started(allocCancel, browserCancel context.CancelFunc, pid int) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.stopped {
+		browserCancel()
+		allocCancel()
+		return false
+	}
+	b.allocCancel = allocCancel
+	b.browserCancel = browserCancel
+	b.pid = pid
+	return true
+}
+
+// Supervise waits for the browser behind ctx to exit and starts a new one, unless Stop was called.
+// onRestart receives the context of the new browser.
+func (b *localBrowser) Supervise(ctx context.Context, onRestart func(context.Context)) {
+	<-ctx.Done()
+
+	delay := time.Second
+	for {
+		if b.isStopped() {
+			return
+		}
+		b.logger.Warnf("Local Chrome exited, restarting in %v...", delay)
+		time.Sleep(delay)
+
+		browserCtx, err := b.Start()
+		if err == nil {
+			onRestart(browserCtx)
+			go b.Supervise(browserCtx, onRestart)
+			return
+		}
+		if err == ErrChromeStopped {
+			return
+		}
+		b.logger.Error("Local Chrome restart error: ", err)
+
+		delay *= 2
+		if delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
+
+// Stop closes the browser and disables the supervisor.
+func (b *localBrowser) Stop() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.stopped = true
+	if b.browserCancel != nil {
+		b.browserCancel()
+	}
+	if b.allocCancel != nil {
+		b.allocCancel()
+	}
+}
+
+// Pid returns the process id of the running browser.
+func (b *localBrowser) Pid() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.pid
+}
+
+func (b *localBrowser) isStopped() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.stopped
+}
+
+func (b *localBrowser) options(execPath string) []chromedp.ExecAllocatorOption {
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	opts = append(opts, chromedp.ExecPath(execPath))
+
+	if !b.config.headless() {
+		opts = append(opts, chromedp.Flag("headless", false))
+	}
+	if b.config.NoSandbox {
+		opts = append(opts, chromedp.NoSandbox)
+	}
+	if b.config.ProxyServer != "" {
+		opts = append(opts, chromedp.ProxyServer(b.config.ProxyServer))
+	}
+	if b.config.UserDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(b.config.UserDataDir))
+	}
+	for name, value := range b.config.Flags {
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+
+	return opts
+}
+
+// findChrome returns the configured binary or the first Chrome/Chromium found on the system.
+func findChrome(execPath string) (string, error) {
+	if execPath != "" {
+		path, err := exec.LookPath(execPath)
+		if err != nil {
+			return "", ErrChromeNotFound
+		}
+		return path, nil
+	}
+
+	var locations []string
+	switch runtime.GOOS {
+	case "darwin":
+		locations = []string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		}
+	case "windows":
+		locations = []string{
+			"chrome",
+			"chrome.exe",
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Google\Chrome\Application\chrome.exe`),
+		}
+	default:
+		locations = []string{
+			"headless_shell",
+			"headless-shell",
+			"chromium",
+			"chromium-browser",
+			"google-chrome",
+			"google-chrome-stable",
+			"/usr/bin/google-chrome",
+			"/snap/bin/chromium",
+			"chrome",
+		}
+	}
+
+	for _, location := range locations {
+		if path, err := exec.LookPath(location); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", ErrChromeNotFound
+}
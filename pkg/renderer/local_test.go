@@ -0,0 +1,24 @@
+package renderer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_localBrowser_started(t *testing.T) {
+	b := newLocalBrowser(LocalConfig{}, nil)
+	closed := 0
+	cancel := func() { closed++ }
+
+	assert.True(t, b.started(cancel, cancel, 42))
+	assert.Equal(t, 42, b.Pid())
+	assert.Equal(t, 0, closed)
+
+	// a browser launched while Stop was called is closed, not kept running
+	b.Stop()
+	assert.Equal(t, 2, closed)
+	assert.False(t, b.started(cancel, cancel, 43))
+	assert.Equal(t, 4, closed)
+	assert.Equal(t, 42, b.Pid())
+	assert.True(t, b.isStopped())
+}
@@ -0,0 +1,119 @@
+package renderer
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale returns the locale the page is rendered in for a crawler sending acceptLanguage.
+// It is the fixed locale of the host, the best of its supported locales or empty
+// when the host doesn't vary by language.
+func (r *Renderer) Locale(requestURL, acceptLanguage string) string {
+	host := r.config.hostConfig(requestURL)
+	if host.Locale != "" {
+		return host.Locale
+	}
+	return negotiateLocale(host.Locales, acceptLanguage)
+}
+
+// Locales returns the locale variants the host of requestURL is cached in, nil if it has none.
+func (r *Renderer) Locales(requestURL string) []string {
+	host := r.config.hostConfig(requestURL)
+	if host.Locale != "" {
+		return []string{host.Locale}
+	}
+	return host.Locales
+}
+
+// negotiateLocale picks the supported locale matching acceptLanguage best, the first one by default.
+func negotiateLocale(supported []string, acceptLanguage string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		for _, locale := range supported {
+			if strings.EqualFold(locale, tag) {
+				return locale
+			}
+		}
+		base := strings.SplitN(tag, "-", 2)[0]
+		for _, locale := range supported {
+			if strings.EqualFold(strings.SplitN(locale, "-", 2)[0], base) {
+				return locale
+			}
+		}
+	}
+	return supported[0]
+}
+
+// parseAcceptLanguage returns the language tags of the header by descending quality.
+func parseAcceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.TrimSpace(fields[0])
+		if name == "" || name == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	res := make([]string, len(tags))
+	for i, t := range tags {
+		res[i] = t.name
+	}
+	return res
+}
+
+// emulateLocale makes the page see the locale in Intl, navigator.language(s) and Accept-Language,
+// and the timezone in Date.
+func emulateLocale(locale, timezone string) []chromedp.Action {
+	var actions []chromedp.Action
+	if locale != "" {
+		actions = append(actions,
+			emulation.SetLocaleOverride().WithLocale(strings.Replace(locale, "-", "_", -1)),
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				_, _, _, userAgent, _, err := browser.GetVersion().Do(ctx)
+				if err != nil {
+					return err
+				}
+				return emulation.SetUserAgentOverride(userAgent).WithAcceptLanguage(acceptLanguage(locale)).Do(ctx)
+			}),
+		)
+	}
+	if timezone != "" {
+		actions = append(actions, emulation.SetTimezoneOverride(timezone))
+	}
+	return actions
+}
+
+// acceptLanguage returns the header for locale, e.g. "fr-CH,fr;q=0.9".
+func acceptLanguage(locale string) string {
+	base := strings.SplitN(locale, "-", 2)[0]
+	if base == locale {
+		return locale
+	}
+	return locale + "," + base + ";q=0.9"
+}
@@ -0,0 +1,23 @@
+package renderer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_negotiateLocale(t *testing.T) {
+	supported := []string{"en-US", "fr-FR", "de-DE"}
+
+	assert.Equal(t, "fr-FR", negotiateLocale(supported, "fr-FR,fr;q=0.9,en;q=0.8"))
+	assert.Equal(t, "fr-FR", negotiateLocale(supported, "fr-CH, fr;q=0.9"))
+	assert.Equal(t, "de-DE", negotiateLocale(supported, "it;q=0.9, de;q=0.5"))
+	assert.Equal(t, "de-DE", negotiateLocale(supported, "en;q=0.1, de"))
+	assert.Equal(t, "en-US", negotiateLocale(supported, ""))
+	assert.Equal(t, "en-US", negotiateLocale(supported, "ja, *;q=0.5"))
+	assert.Empty(t, negotiateLocale(nil, "fr"))
+}
+
+func Test_acceptLanguage(t *testing.T) {
+	assert.Equal(t, "fr-CH,fr;q=0.9", acceptLanguage("fr-CH"))
+	assert.Equal(t, "fr", acceptLanguage("fr"))
+}
@@ -0,0 +1,282 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/performance"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errMemoryUnknown = errors.New("error: browser memory usage not available")
+
+const defaultCheckInterval = time.Minute
+
+// recycler counts renders and blocks new ones while the browser is drained and restarted.
+type recycler struct {
+	config   RecycleConfig
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	inFlight int
+	renders  int
+	started  time.Time
+	draining bool
+}
+
+func newRecycler(config RecycleConfig) *recycler {
+	c := &recycler{
+		config:  config,
+		started: time.Now(),
+	}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Acquire waits for a running recycle to finish and registers an in-flight render.
+func (c *recycler) Acquire() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for c.draining {
+		c.cond.Wait()
+	}
+	c.inFlight++
+}
+
+// Release unregisters a render. It returns the reason when the browser has to be recycled,
+// in that case new renders are already held until Done.
+func (c *recycler) Release(heapMB int) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.inFlight--
+	c.renders++
+	c.cond.Broadcast()
+
+	reason := ""
+	switch {
+	case c.config.MaxRenders > 0 && c.renders >= c.config.MaxRenders:
+		reason = fmt.Sprintf("%d renders", c.renders)
+	case c.config.MaxHeapMB > 0 && heapMB > c.config.MaxHeapMB:
+		reason = fmt.Sprintf("JS heap %d MB", heapMB)
+	}
+	if reason == "" || c.draining {
+		return ""
+	}
+	c.draining = true
+	return reason
+}
+
+// Begin holds new renders, it returns false if a recycle is already running.
+func (c *recycler) Begin() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.draining {
+		return false
+	}
+	c.draining = true
+	return true
+}
+
+// Drain waits for in-flight renders to finish.
+func (c *recycler) Drain() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for c.inFlight > 0 {
+		c.cond.Wait()
+	}
+}
+
+// Done resets the counters and lets held renders continue.
+func (c *recycler) Done() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.renders = 0
+	c.started = time.Now()
+	c.draining = false
+	c.cond.Broadcast()
+}
+
+func (c *recycler) age() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return time.Since(c.started)
+}
+
+// watchRecycle periodically checks the browser age and memory.
+func (r *Renderer) watchRecycle() {
+	interval := time.Duration(r.config.Recycle.CheckInterval)
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+
+	for range time.Tick(interval) {
+		if !r.recyclable() {
+			continue
+		}
+		reason := ""
+
+		if maxAge := time.Duration(r.config.Recycle.MaxAge); maxAge > 0 && r.recycler.age() >= maxAge {
+			reason = fmt.Sprintf("age %v", r.recycler.age().Round(time.Second))
+		}
+
+		if reason == "" && r.config.Recycle.MaxMemoryMB > 0 {
+			usage, err := r.memoryUsage()
+			if err != nil {
+				r.logger.Debug("Browser memory: ", err)
+			} else if mb := int(usage >> 20); mb > r.config.Recycle.MaxMemoryMB {
+				reason = fmt.Sprintf("memory %d MB", mb)
+			}
+		}
+
+		if reason != "" && r.recycler.Begin() {
+			r.recycle(reason)
+		}
+	}
+}
+
+// recyclable reports whether the browser in use can be restarted: a local one, or the docker
+// container unless it is shared. A remote browser may run renders of other processes.
+func (r *Renderer) recyclable() bool {
+	_, isRemote := r.browser()
+	return !isRemote || r.containerPath() != "" && !r.config.Recycle.SharedContainer
+}
+
+// containerPath returns the docker binary when the browser runs in the container, "" otherwise.
+func (r *Renderer) containerPath() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.isRemote || r.config.Mode == ModeRemote {
+		return ""
+	}
+	return r.dockerPath
+}
+
+// recycle drains in-flight renders and restarts the browser. The caller has to hold new renders first.
+func (r *Renderer) recycle(reason string) {
+	defer r.recycler.Done()
+
+	if !r.recyclable() {
+		r.logger.Warnf("Chrome is shared, not recycled (%s)", reason)
+		return
+	}
+
+	r.logger.Warnf("Recycling Chrome (%s), waiting for in-flight renders...", reason)
+	r.recycler.Drain()
+
+	if _, isRemote := r.browser(); !isRemote {
+		r.setupLocal()
+	} else {
+		r.Cancel()
+		if err := r.rebootContainer(); err != nil {
+			r.logger.Error("Recycle container error: ", err)
+		}
+		r.Setup()
+	}
+	r.logger.Warn("Chrome recycled")
+}
+
+// jsHeapMB returns the used JS heap of the page, 0 if metrics are not available.
+func jsHeapMB(ctx context.Context) int {
+	metrics, err := performance.GetMetrics().Do(ctx)
+	if err != nil {
+		return 0
+	}
+	for _, m := range metrics {
+		if m.Name == "JSHeapUsedSize" {
+			return int(m.Value) >> 20
+		}
+	}
+	return 0
+}
+
+func (r *Renderer) memoryUsage() (uint64, error) {
+	if local, isRemote := r.browser(); !isRemote && local != nil {
+		return processTreeRSS(local.Pid())
+	}
+	if dockerPath := r.containerPath(); dockerPath != "" {
+		out, err := exec.Command(dockerPath, "stats", "--no-stream", "--format", "{{.MemUsage}}", container).Output()
+		if err != nil {
+			return 0, err
+		}
+		return parseMemUsage(string(out))
+	}
+	return 0, errMemoryUnknown
+}
+
+// processTreeRSS sums the resident memory of a process and its children from /proc.
+func processTreeRSS(pid int) (uint64, error) {
+	if pid == 0 {
+		return 0, errMemoryUnknown
+	}
+
+	dirs, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil || len(dirs) == 0 {
+		return 0, errMemoryUnknown
+	}
+
+	parents := map[int]int{}
+	rss := map[int]uint64{}
+	for _, file := range dirs {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// the command may contain spaces, fields are counted after its closing bracket
+		stat := string(b)
+		i := strings.LastIndex(stat, ")")
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(stat[i+1:])
+		if len(fields) < 22 {
+			continue
+		}
+		p, _ := strconv.Atoi(filepath.Base(filepath.Dir(file)))
+		parents[p], _ = strconv.Atoi(fields[1])
+		pages, _ := strconv.ParseUint(fields[21], 10, 64)
+		rss[p] = pages * uint64(os.Getpagesize())
+	}
+
+	if _, ok := rss[pid]; !ok {
+		return 0, errMemoryUnknown
+	}
+
+	var total uint64
+	for p := range rss {
+		for q := p; q > 1; q = parents[q] {
+			if q == pid {
+				total += rss[p]
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+// parseMemUsage parses the used part of docker stats MemUsage, e.g. "123.4MiB / 1.944GiB".
+func parseMemUsage(s string) (uint64, error) {
+	used := strings.TrimSpace(strings.Split(s, "/")[0])
+	units := []struct {
+		suffix string
+		size   float64
+	}{
+		{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+		{"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3}, {"B", 1},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(used, u.suffix) {
+			v, err := strconv.ParseFloat(strings.TrimSuffix(used, u.suffix), 64)
+			if err != nil {
+				return 0, err
+			}
+			return uint64(v * u.size), nil
+		}
+	}
+	return 0, fmt.Errorf("error: unknown memory usage %q", s)
+}
@@ -0,0 +1,51 @@
+package renderer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_parseMemUsage(t *testing.T) {
+	v, err := parseMemUsage("512MiB / 1.944GiB\n")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(512<<20), v)
+
+	v, err = parseMemUsage("1.5GiB / 2GiB")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3<<29), v)
+
+	_, err = parseMemUsage("--")
+	assert.NotNil(t, err)
+}
+
+func TestRenderer_recyclable(t *testing.T) {
+	r := &Renderer{}
+	assert.True(t, r.recyclable())
+
+	r.isRemote = true
+	assert.False(t, r.recyclable())
+	r.dockerPath = "/usr/bin/docker"
+	assert.True(t, r.recyclable())
+
+	r.config.Recycle.SharedContainer = true
+	assert.False(t, r.recyclable())
+	r.config = Config{Mode: ModeRemote}
+	assert.False(t, r.recyclable())
+}
+
+func Test_recycler(t *testing.T) {
+	c := newRecycler(RecycleConfig{MaxRenders: 2, MaxHeapMB: 100})
+
+	c.Acquire()
+	assert.Empty(t, c.Release(10))
+	c.Acquire()
+	assert.NotEmpty(t, c.Release(10))
+	assert.False(t, c.Begin())
+	c.Done()
+
+	c.Acquire()
+	assert.NotEmpty(t, c.Release(200))
+	c.Drain()
+	c.Done()
+	assert.True(t, c.Begin())
+}
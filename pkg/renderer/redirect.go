@@ -0,0 +1,68 @@
+package renderer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RedirectError is returned by DoRender when the requested URL answered with an HTTP redirect,
+// or when the page moved itself to another URL (window.location, history.replaceState...)
+// instead of rendering the requested one.
+type RedirectError struct {
+	Location string
+	Status   int
+	// ClientSide tells the page moved itself, the other redirects are HTTP 3xx responses.
+	ClientSide bool
+}
+
+func (e *RedirectError) Error() string {
+	if e.ClientSide {
+		return fmt.Sprintf("err: client side redirect %d to %s", e.Status, e.Location)
+	}
+	return fmt.Sprintf("err: redirect %d to %s", e.Status, e.Location)
+}
+
+// redirected reports whether finalURL is another page than requestURL. The URLs are compared
+// normalized: the fragment, default ports, the case of scheme and host, and the encoding and
+// order of the query are ignored.
+func redirected(requestURL, finalURL string) bool {
+	r, ok := normalizeURL(requestURL)
+	if !ok {
+		return false
+	}
+	f, ok := normalizeURL(finalURL)
+	if !ok {
+		return false
+	}
+	return r != f
+}
+
+func normalizeURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return scheme + "://" + host + path + "?" + u.RawQuery, true
+	}
+	return scheme + "://" + host + path + "?" + query.Encode(), true
+}
+
+func (c HostConfig) redirectStatus() int {
+	if c.RedirectStatus == http.StatusMovedPermanently {
+		return c.RedirectStatus
+	}
+	return http.StatusFound
+}
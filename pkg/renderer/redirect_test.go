@@ -0,0 +1,17 @@
+package renderer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_redirected(t *testing.T) {
+	assert.False(t, redirected("https://example.com", "https://example.com/#top"))
+	assert.False(t, redirected("https://Example.com:443/a?q=a%20b&x=1", "https://example.com/a?x=1&q=a+b"))
+	assert.False(t, redirected("https://example.com/caf%C3%A9", "https://example.com/café"))
+	assert.False(t, redirected("https://example.com/", "about:blank"))
+
+	assert.True(t, redirected("https://example.com/a", "https://example.com/b"))
+	assert.True(t, redirected("https://example.com/a?x=1", "https://example.com/a?x=2"))
+	assert.True(t, redirected("http://example.com/", "https://example.com/"))
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/performance"
 	"github.com/chromedp/chromedp"
 	"github.com/goprerender/prerender/pkg/log"
 	"net/http"
@@ -23,6 +25,12 @@ type Renderer struct {
 	dockerPath   string
 	lastStart    time.Time
 	mutex        sync.Mutex
+	config       Config
+	local        *localBrowser
+	recycler     *recycler
+	endpoint     string
+	limiter      *limiter
+	scheduler    *scheduler
 	logger       log.Logger
 }
 
@@ -32,19 +40,91 @@ func (r *Renderer) IsRestarting() bool {
 	return r.isRestarting
 }
 
-func NewRenderer(logger log.Logger) *Renderer {
+func NewRenderer(config Config, logger log.Logger) *Renderer {
 	r := &Renderer{
-		logger: logger,
+		config:    config,
+		recycler:  newRecycler(config.Recycle),
+		limiter:   newLimiter(),
+		scheduler: newScheduler(config.Scheduler),
+		logger:    logger,
 	}
 	r.Setup()
+	if config.Recycle.MaxAge > 0 || config.Recycle.MaxMemoryMB > 0 {
+		go r.watchRecycle()
+	}
 	return r
 }
 
 var ErrNotResponding = errors.New("error: Chrome not responding")
 
+// renderTimeout bounds a render in Chrome, and its wait for the rate limit of the host.
+const renderTimeout = 10 * time.Second
+
+// Options are the optional parts of a render.
+type Options struct {
+	// HAR records the network activity into Diagnostics.HAR.
+	HAR bool
+	// Locale is emulated in the page, see Renderer.Locale.
+	Locale string
+	// Traffic selects the priority of the render and its rate limit budget.
+	Traffic Traffic
+}
+
+// waitLimit waits for the rate limit of the host of requestURL, at most as long as a render may run.
+func (r *Renderer) waitLimit(requestURL string, traffic Traffic) (release func(), err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), renderTimeout)
+	defer cancel()
+	release, err = r.limiter.wait(ctx, requestURL, traffic, r.config.hostConfig(requestURL).RateLimit)
+	if err != nil {
+		r.logger.Warnf("Render of %s not started: %s", requestURL, err)
+	}
+	return release, err
+}
+
+// QueueStats returns the render scheduler numbers per Traffic class.
+func (r *Renderer) QueueStats() []QueueStats {
+	return r.scheduler.Stats()
+}
+
 func (r *Renderer) DoRender(requestURL string) (string, error) {
+	res, _, err := r.Render(requestURL, Options{})
+	if err != nil {
+		var re *RedirectError
+		if errors.As(err, &re) {
+			return re.Location, err
+		}
+		return "", err
+	}
+	return res.HTML, nil
+}
+
+// Render renders the page like DoRender with its metadata and reports what happened in it.
+// The diagnostics are returned on errors too, they are nil if Chrome was never reached.
+func (r *Renderer) Render(requestURL string, opts Options) (*Result, *Diagnostics, error) {
 	var res string
+	var meta Metadata
+	var location string
+	var d *collector
 	var attempts = 0
+	var invalid = 0
+	var found bool
+	var heapMB int
+
+	release, err := r.waitLimit(requestURL, opts.Traffic)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	r.scheduler.Acquire(opts.Traffic)
+	defer r.scheduler.Release(opts.Traffic)
+
+	r.recycler.Acquire()
+	defer func() {
+		if reason := r.recycler.Release(heapMB); reason != "" {
+			go r.recycle(reason)
+		}
+	}()
 
 	startTime := time.Now()
 
@@ -54,33 +134,76 @@ start:
 		time.Sleep(5 * time.Second)
 		attempts++
 		if attempts > 5 {
-			return res, ErrNotResponding
+			return nil, nil, ErrNotResponding
 		}
 		goto start
 	}
 
-	newTabCtx, cancel := chromedp.NewContext(r.allocatorCtx)
+	newTabCtx, cancel := chromedp.NewContext(r.allocator())
 	defer cancel()
 
 	//new context with timeout
-	ctx, cancel := context.WithTimeout(newTabCtx, time.Second*10)
+	ctx, cancel := context.WithTimeout(newTabCtx, renderTimeout)
 	defer cancel()
 
-next:
-	headers := network.Headers{"X-Prerender-Next": "1"}
+	host := r.config.hostConfig(requestURL)
+
+	d = newCollector(requestURL, r.Endpoint(), opts.HAR)
+	d.Listen(ctx)
+
+	b := newBlocker(host.Block, requestURL)
+	b.Listen(ctx)
 
-	err := chromedp.Run(ctx,
-		network.SetBlockedURLS([]string{"google-analytics.com", "mc.yandex.ru", "maps.googleapis.com", "googletagmanager.com"}),
-		network.SetExtraHTTPHeaders(headers),
-		chromedp.Navigate(requestURL),
-		//chromedp.WaitReady("body"),
-		chromedp.OuterHTML("html", &res, chromedp.ByQuery),
-	)
+	i := newInterceptor(b, r.config.Origins, host.Headers, host.BasicAuth, requestURL)
+	i.Listen(ctx)
+
+	// the tab is set up once, retries only load the page again
+	setup := append(b.Actions(), i.Actions()...)
+	setup = append(setup, network.SetExtraHTTPHeaders(network.Headers{"X-Prerender-Next": "1"}))
+	if len(host.Cookies) > 0 {
+		setup = append(setup, network.SetCookies(cookieParams(host.Cookies, requestURL)))
+	}
+	setup = append(setup, emulateLocale(opts.Locale, host.Timezone)...)
+	if host.BeforeLoad != "" {
+		setup = append(setup, beforeLoad(host.BeforeLoad))
+	}
+	if r.config.Recycle.MaxHeapMB > 0 {
+		setup = append(setup, performance.Enable())
+	}
+
+	load := []chromedp.Action{chromedp.Navigate(requestURL)}
+	//chromedp.WaitReady("body"),
+	load = append(load, afterLoad(host.AfterLoad)...)
+	load = append(load, chromedp.Location(&location))
+	if v := host.Validate; v != nil && v.Selector != "" {
+		load = append(load, chromedp.Evaluate(selectorScript(v.Selector), &found))
+	}
+	load = append(load, chromedp.Evaluate(metadataScript, &meta))
+	if host.ShadowDOM {
+		load = append(load, shadowHTML(&res))
+	} else {
+		load = append(load, chromedp.OuterHTML("html", &res, chromedp.ByQuery))
+	}
+	if r.config.Recycle.MaxHeapMB > 0 {
+		load = append(load, chromedp.ActionFunc(func(ctx context.Context) error {
+			heapMB = jsHeapMB(ctx)
+			return nil
+		}))
+	}
+
+	setupErr := chromedp.Run(ctx, setup...)
+
+next:
+	err = setupErr
+	if err == nil {
+		err = chromedp.Run(ctx, load...)
+	}
 
 	endTime := time.Now()
 
 	delta := endTime.Sub(startTime).Seconds()
 	r.logger.Debugf("Duration: %f seconds", delta)
+	r.logger.Debugf("Blocked requests: %s, url: %s", b.Summary(), requestURL)
 
 	if err != nil {
 		r.logger.Error("ChromeDP error: ", err, ", url:", requestURL)
@@ -95,7 +218,7 @@ next:
 				err := r.Restart()
 				if err != nil {
 					r.logger.Warn("Error restarting container...")
-					return "", err
+					return nil, d.Result(startTime, err), err
 				}
 				r.logger.Warn("Chrome setup complete...")
 				attempts = 0
@@ -110,12 +233,6 @@ next:
 			goto start
 		}
 
-		if strings.HasPrefix(err.Error(), "exec: \"google-chrome\": executable file not found in") {
-			r.isStarted = false
-			r.Setup()
-			goto start
-		}
-
 		if attempts < 3 {
 			attempts++
 			r.logger.Warn("ChromeDP sleep for 1 sec, att: ", attempts)
@@ -132,16 +249,57 @@ next:
 				time.Sleep(3 * time.Second)
 				goto start
 			}
+			if setupErr != nil {
+				// the tab was not set up, the retry sets a new one up
+				cancel()
+				goto start
+			}
 			goto next
 		}
 
-		return "", err
+		return nil, d.Result(startTime, err), err
+	}
+
+	navURL, navStatus := d.navigation()
+	if navStatus != 0 && redirected(requestURL, navURL) {
+		r.logger.Infof("Redirect: %s -> %s", requestURL, navURL)
+		return nil, d.Result(startTime, nil), &RedirectError{Location: navURL, Status: int(navStatus)}
+	}
+	if navURL == "" {
+		navURL = requestURL
+	}
+	if redirected(navURL, location) {
+		r.logger.Infof("Client side redirect: %s -> %s", requestURL, location)
+		return nil, d.Result(startTime, nil), &RedirectError{Location: location, Status: host.redirectStatus(), ClientSide: true}
+	}
+
+	if reason := host.Validate.check(res, d.status(), found); reason != "" {
+		r.logger.Warnf("Render not valid: %s, url: %s", reason, requestURL)
+		if invalid < host.Validate.retries() {
+			invalid++
+			cancel()
+			time.Sleep(1 * time.Second)
+			goto start
+		}
+		err := &ValidationError{Reason: reason}
+		return nil, d.Result(startTime, err), err
 	}
 
-	return res, nil
+	meta.FinalURL = location
+	meta.Status, meta.Headers = d.response()
+	return &Result{HTML: res, Metadata: meta}, d.Result(startTime, nil), nil
 }
 
 func (r *Renderer) Setup() {
+	switch r.config.Mode {
+	case ModeLocal:
+		r.setupLocal()
+		return
+	case ModeRemote:
+		r.setupRemote()
+		return
+	}
+
 	if !r.isStarted {
 		if r.IsRestarting() {
 			return
@@ -152,21 +310,114 @@ func (r *Renderer) Setup() {
 		}
 	}
 
-	r.logger.Infof("Try to setup Chrome")
-	devToolWsUrl, err := GetDebugURL(r.logger)
+	if r.config.Mode == ModeDocker {
+		r.setupRemote()
+		return
+	}
 
+	r.logger.Infof("Try to setup Chrome")
+	devToolWsUrl, err := GetDebugURL(r.config.remoteURL(), r.logger)
 	if err == nil {
-		r.allocatorCtx, r.cancel = chromedp.NewRemoteAllocator(context.Background(), devToolWsUrl)
-		r.isRemote = true
-	} else {
-		r.logger.Warn("Trying to connect to local chrome")
-		r.allocatorCtx, r.cancel = context.WithCancel(context.Background())
-		r.isRemote = false
+		r.setAllocator(chromedp.NewRemoteAllocator(context.Background(), devToolWsUrl))
+		r.setEndpoint(devToolWsUrl)
+		r.setRemote()
+		return
+	}
+
+	r.logger.Warn("Trying to connect to local chrome")
+	r.setupLocal()
+}
+
+// setupRemote connects to the Chrome at the configured DevTools endpoint.
+// If it is not reachable the endpoint is still used, so renders fail to dial and trigger a restart.
+func (r *Renderer) setupRemote() {
+	r.logger.Infof("Try to setup Chrome")
+	r.setRemote()
+
+	devToolWsUrl, err := GetDebugURL(r.config.remoteURL(), r.logger)
+	if err != nil {
+		r.logger.Error("Remote Chrome not available: ", err)
+		devToolWsUrl = "ws" + strings.TrimPrefix(r.config.remoteURL(), "http")
+	}
+	r.setAllocator(chromedp.NewRemoteAllocator(context.Background(), devToolWsUrl))
+	r.setEndpoint(devToolWsUrl)
+}
+
+// setupLocal launches a supervised local Chrome process.
+func (r *Renderer) setupLocal() {
+	local := newLocalBrowser(r.config.Local, r.logger)
+
+	r.mutex.Lock()
+	previous := r.local
+	r.local, r.isRemote = local, false
+	r.mutex.Unlock()
+
+	if previous != nil {
+		previous.Stop()
+	}
+
+	browserCtx, err := local.Start()
+	if err != nil {
+		// a done context makes the supervisor keep trying to start Chrome
+		r.logger.Error("Local Chrome not started: ", err)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		browserCtx = ctx
+	}
+	r.setAllocator(browserCtx, local.Stop)
+
+	go local.Supervise(browserCtx, func(ctx context.Context) {
+		r.setAllocator(ctx, local.Stop)
+	})
+}
+
+func (r *Renderer) setAllocator(ctx context.Context, cancel context.CancelFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.allocatorCtx, r.cancel = ctx, cancel
+}
+
+func (r *Renderer) setRemote() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.isRemote = true
+}
+
+// browser returns the local browser and whether the browser in use is a remote one.
+func (r *Renderer) browser() (*localBrowser, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.local, r.isRemote
+}
+
+func (r *Renderer) setEndpoint(endpoint string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.endpoint = endpoint
+}
+
+// Endpoint returns the Chrome in use: its DevTools URL, or "local" and its pid.
+func (r *Renderer) Endpoint() string {
+	local, isRemote := r.browser()
+
+	r.mutex.Lock()
+	endpoint := r.endpoint
+	r.mutex.Unlock()
+
+	if !isRemote && local != nil {
+		return fmt.Sprintf("local:%d", local.Pid())
 	}
+	return endpoint
+}
+
+func (r *Renderer) allocator() context.Context {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.allocatorCtx
 }
 
 func (r *Renderer) Restart() error {
-	if r.isRemote {
+	if _, isRemote := r.browser(); isRemote {
 		if r.IsRestarting() {
 			err := r.rebootContainer()
 			if err != nil {
@@ -179,12 +430,15 @@ func (r *Renderer) Restart() error {
 }
 
 func (r *Renderer) Cancel() {
-	r.cancel()
+	r.mutex.Lock()
+	cancel := r.cancel
+	r.mutex.Unlock()
+	cancel()
 }
 
-func GetDebugURL(logger log.Logger) (string, error) {
+func GetDebugURL(remoteURL string, logger log.Logger) (string, error) {
 	logger.Infof("Try to get data from remote Chrome...")
-	resp, err := http.Get("http://localhost:9222/json/version")
+	resp, err := http.Get(strings.TrimRight(remoteURL, "/") + "/json/version")
 	if err != nil {
 		logger.Warn("Error get debug URL: ", err)
 		return "", err
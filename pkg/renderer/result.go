@@ -0,0 +1,32 @@
+package renderer
+
+// Result is a rendered page.
+type Result struct {
+	HTML string
+	Metadata
+}
+
+// Metadata describes a rendered page.
+type Metadata struct {
+	// FinalURL is the location of the page once loaded.
+	FinalURL string `json:"finalUrl"`
+	// Status is the HTTP status of the document.
+	Status int64 `json:"status"`
+	// Headers are the response headers of the document.
+	Headers     map[string]string `json:"headers,omitempty"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	// Canonical is the absolute URL of the canonical link.
+	Canonical string `json:"canonical,omitempty"`
+}
+
+// metadataScript evaluates to the page part of the Metadata.
+const metadataScript = `(() => {
+	const description = document.querySelector('meta[name="description" i]');
+	const canonical = document.querySelector('link[rel="canonical" i]');
+	return {
+		title: document.title,
+		description: description ? description.getAttribute("content") || "" : "",
+		canonical: canonical ? canonical.href : ""
+	};
+})()`
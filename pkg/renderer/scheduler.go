@@ -0,0 +1,176 @@
+package renderer
+
+import (
+	"sync"
+	"time"
+)
+
+// SchedulerConfig limits the renders running at once, waiting renders start by Traffic priority.
+type SchedulerConfig struct {
+	// Concurrency is the number of renders running at once, 4 by default.
+	Concurrency int `json:"concurrency"`
+	// MaxWait is how long a render waits before it goes ahead of higher priorities, 30s by default.
+	MaxWait Duration `json:"maxWait"`
+}
+
+func (c SchedulerConfig) concurrency() int {
+	if c.Concurrency <= 0 {
+		return 4
+	}
+	return c.Concurrency
+}
+
+func (c SchedulerConfig) maxWait() time.Duration {
+	if c.MaxWait <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.MaxWait)
+}
+
+// QueueStats are the scheduler numbers of one Traffic class.
+type QueueStats struct {
+	Traffic string `json:"traffic"`
+	Queued  int    `json:"queued"`
+	Running int    `json:"running"`
+	Started uint64 `json:"started"`
+	// Promoted are the renders started ahead of higher priorities because they waited MaxWait.
+	Promoted uint64 `json:"promoted"`
+	// AvgWait and MaxWait are in seconds.
+	AvgWait float64 `json:"avgWait"`
+	MaxWait float64 `json:"maxWait"`
+}
+
+type waiter struct {
+	ready chan struct{}
+	since time.Time
+}
+
+type classStats struct {
+	running  int
+	started  uint64
+	promoted uint64
+	waited   time.Duration
+	maxWait  time.Duration
+}
+
+// scheduler hands the render slots out by priority, Interactive first, Background last.
+// A render waiting longer than MaxWait goes first whatever its class, so background work
+// is slowed down by live traffic but never starved.
+type scheduler struct {
+	mutex   sync.Mutex
+	config  SchedulerConfig
+	running int
+	queues  [trafficClasses][]*waiter
+	stats   [trafficClasses]classStats
+}
+
+func newScheduler(config SchedulerConfig) *scheduler {
+	return &scheduler{config: config}
+}
+
+// Acquire waits for a render slot, Release must be called when the render ends.
+func (s *scheduler) Acquire(traffic Traffic) {
+	s.mutex.Lock()
+	if s.running < s.config.concurrency() {
+		s.start(traffic, 0, false)
+		s.mutex.Unlock()
+		return
+	}
+	w := &waiter{ready: make(chan struct{}), since: time.Now()}
+	s.queues[traffic] = append(s.queues[traffic], w)
+	s.mutex.Unlock()
+
+	<-w.ready
+}
+
+func (s *scheduler) Release(traffic Traffic) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.running--
+	s.stats[traffic].running--
+	for s.running < s.config.concurrency() {
+		w, t, promoted := s.next()
+		if w == nil {
+			return
+		}
+		s.start(t, time.Since(w.since), promoted)
+		close(w.ready)
+	}
+}
+
+func (s *scheduler) start(traffic Traffic, waited time.Duration, promoted bool) {
+	s.running++
+	st := &s.stats[traffic]
+	st.running++
+	st.started++
+	st.waited += waited
+	if waited > st.maxWait {
+		st.maxWait = waited
+	}
+	if promoted {
+		st.promoted++
+	}
+}
+
+// next dequeues the oldest waiter past MaxWait, else the first one of the highest priority.
+func (s *scheduler) next() (*waiter, Traffic, bool) {
+	starved := Traffic(-1)
+	for t := range s.queues {
+		q := s.queues[t]
+		if len(q) == 0 || time.Since(q[0].since) < s.config.maxWait() {
+			continue
+		}
+		if starved < 0 || q[0].since.Before(s.queues[starved][0].since) {
+			starved = Traffic(t)
+		}
+	}
+	first := s.first()
+	if starved >= 0 {
+		return s.pop(starved), starved, starved != first
+	}
+	if first >= 0 {
+		return s.pop(first), first, false
+	}
+	return nil, 0, false
+}
+
+// first returns the highest priority with waiters, -1 if none.
+func (s *scheduler) first() Traffic {
+	for t := range s.queues {
+		if len(s.queues[t]) > 0 {
+			return Traffic(t)
+		}
+	}
+	return -1
+}
+
+func (s *scheduler) pop(t Traffic) *waiter {
+	w := s.queues[t][0]
+	s.queues[t] = s.queues[t][1:]
+	return w
+}
+
+// Stats returns the numbers of every Traffic class by priority.
+func (s *scheduler) Stats() []QueueStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	res := make([]QueueStats, 0, trafficClasses)
+	for t := range s.queues {
+		st := s.stats[t]
+		qs := QueueStats{
+			Traffic:  Traffic(t).String(),
+			Queued:   len(s.queues[t]),
+			Running:  st.running,
+			Started:  st.started,
+			Promoted: st.promoted,
+			MaxWait:  st.maxWait.Seconds(),
+		}
+		if st.started > 0 {
+			qs.AvgWait = st.waited.Seconds() / float64(st.started)
+		}
+		res = append(res, qs)
+	}
+	return res
+}
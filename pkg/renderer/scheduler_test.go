@@ -0,0 +1,60 @@
+package renderer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// queue adds a waiter of traffic to a scheduler without blocking, it returns its start channel.
+func queue(s *scheduler, traffic Traffic, since time.Time) chan struct{} {
+	w := &waiter{ready: make(chan struct{}), since: since}
+	s.queues[traffic] = append(s.queues[traffic], w)
+	return w.ready
+}
+
+func Test_scheduler(t *testing.T) {
+	s := newScheduler(SchedulerConfig{Concurrency: 1, MaxWait: Duration(time.Minute)})
+	s.Acquire(Interactive)
+
+	background := queue(s, Background, time.Now())
+	recache := queue(s, Recache, time.Now())
+	live := queue(s, Interactive, time.Now())
+
+	s.Release(Interactive)
+	assert.True(t, isClosed(live))
+	assert.False(t, isClosed(recache))
+
+	s.Release(Interactive)
+	assert.True(t, isClosed(recache))
+	assert.False(t, isClosed(background))
+
+	s.Release(Recache)
+	assert.True(t, isClosed(background))
+
+	// a background render waiting too long goes first
+	starved := queue(s, Background, time.Now().Add(-2*time.Minute))
+	live = queue(s, Interactive, time.Now())
+
+	s.Release(Background)
+	assert.True(t, isClosed(starved))
+	assert.False(t, isClosed(live))
+
+	stats := s.Stats()
+	assert.Equal(t, "background", stats[Background].Traffic)
+	assert.Equal(t, uint64(1), stats[Background].Promoted)
+	assert.Equal(t, 1, stats[Interactive].Queued)
+	assert.Equal(t, 1, stats[Background].Running)
+
+	s.Release(Background)
+	assert.True(t, isClosed(live))
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"time"
+)
+
+const (
+	defaultMaxScrolls = 50
+	scrollStep        = 200 * time.Millisecond
+)
+
+// PageAction is a step executed after the page is loaded and before its HTML is captured.
+// Only one of the fields is expected to be set.
+type PageAction struct {
+	// Eval is JavaScript run as the body of an async function.
+	Eval string `json:"eval"`
+	// Click clicks the first element matching the CSS selector, if any.
+	Click string `json:"click"`
+	// AutoScroll scrolls to the bottom until the page height stops growing.
+	AutoScroll bool `json:"autoScroll"`
+	// MaxScrolls limits AutoScroll, 50 by default. Scrolling never takes more than half of the
+	// time left to the render.
+	MaxScrolls int `json:"maxScrolls"`
+	// Wait pauses, e.g. "500ms".
+	Wait Duration `json:"wait"`
+}
+
+// beforeLoad injects the script into every document of the tab before its own scripts run.
+func beforeLoad(script string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(script).Do(ctx)
+		return err
+	})
+}
+
+// afterLoad returns the chromedp actions for the configured page actions.
+func afterLoad(actions []PageAction) []chromedp.Action {
+	var res []chromedp.Action
+	for _, a := range actions {
+		switch {
+		case a.Eval != "":
+			res = append(res, evalAsync(a.Eval))
+		case a.Click != "":
+			selector, _ := json.Marshal(a.Click)
+			res = append(res, evalAsync(fmt.Sprintf(`const el = document.querySelector(%s); if (el) el.click();`, selector)))
+		case a.AutoScroll:
+			res = append(res, autoScroll(a.MaxScrolls))
+		}
+		if a.Wait > 0 {
+			res = append(res, chromedp.Sleep(time.Duration(a.Wait)))
+		}
+	}
+	return res
+}
+
+// autoScroll scrolls the page within the time left to the render.
+func autoScroll(maxScrolls int) chromedp.Action {
+	if maxScrolls <= 0 {
+		maxScrolls = defaultMaxScrolls
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		scrolls := maxScrolls
+		if deadline, ok := ctx.Deadline(); ok {
+			scrolls = scrollBudget(time.Until(deadline), maxScrolls)
+		}
+		if scrolls == 0 {
+			return nil
+		}
+		return evalAsync(fmt.Sprintf(autoScrollScript, scrolls, scrollStep.Milliseconds())).Do(ctx)
+	})
+}
+
+// scrollBudget returns how many scroll steps fit in half of the time left, at most maxScrolls.
+func scrollBudget(left time.Duration, maxScrolls int) int {
+	scrolls := int(left / 2 / scrollStep)
+	if scrolls > maxScrolls {
+		return maxScrolls
+	}
+	if scrolls < 0 {
+		return 0
+	}
+	return scrolls
+}
+
+// evalAsync runs the script as an async function body and waits for it.
+func evalAsync(script string) chromedp.Action {
+	var done bool
+	return chromedp.Evaluate(fmt.Sprintf("(async () => {\n%s\n})().then(() => true)", script), &done,
+		func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		})
+}
+
+// autoScrollScript scrolls down until the height is the same for two steps in a row.
+const autoScrollScript = `
+const sleep = ms => new Promise(r => setTimeout(r, ms));
+let height = 0, same = 0;
+for (let i = 0; i < %d && same < 2; i++) {
+	window.scrollTo(0, document.body.scrollHeight);
+	await sleep(%d);
+	const h = document.body.scrollHeight;
+	same = h === height ? same + 1 : 0;
+	height = h;
+}
+window.scrollTo(0, 0);`
@@ -0,0 +1,14 @@
+package renderer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_scrollBudget(t *testing.T) {
+	assert.Equal(t, 25, scrollBudget(10*time.Second, 50))
+	assert.Equal(t, 5, scrollBudget(10*time.Second, 5))
+	assert.Equal(t, 0, scrollBudget(100*time.Millisecond, 50))
+	assert.Equal(t, 0, scrollBudget(-time.Second, 50))
+}
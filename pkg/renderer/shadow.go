@@ -0,0 +1,44 @@
+package renderer
+
+import "github.com/chromedp/chromedp"
+
+// shadowHTML captures the document like OuterHTML but keeps open shadow roots
+// as declarative shadow DOM: <template shadowrootmode="open">.
+func shadowHTML(res *string) chromedp.Action {
+	return chromedp.Evaluate(shadowSerializer, res)
+}
+
+const shadowSerializer = `(() => {
+const voids = new Set(['area', 'base', 'br', 'col', 'embed', 'hr', 'img', 'input', 'link', 'meta', 'source', 'track', 'wbr']);
+const raw = new Set(['script', 'style', 'xmp', 'iframe', 'noembed', 'noframes', 'plaintext', 'noscript']);
+const text = s => s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/\u00a0/g, '&nbsp;');
+const attr = s => s.replace(/&/g, '&amp;').replace(/"/g, '&quot;').replace(/\u00a0/g, '&nbsp;');
+const children = node => Array.from(node.childNodes, c => serialize(c, node)).join('');
+const serialize = (node, parent) => {
+	switch (node.nodeType) {
+	case Node.ELEMENT_NODE: {
+		const tag = node.localName;
+		let s = '<' + tag;
+		for (const a of node.attributes) {
+			s += ' ' + a.name + '="' + attr(a.value) + '"';
+		}
+		s += '>';
+		if (voids.has(tag)) {
+			return s;
+		}
+		if (node.shadowRoot) {
+			s += '<template shadowrootmode="' + node.shadowRoot.mode + '">' + children(node.shadowRoot) + '</template>';
+		}
+		s += tag === 'template' ? children(node.content) : children(node);
+		return s + '</' + tag + '>';
+	}
+	case Node.TEXT_NODE:
+		return parent && raw.has(parent.localName) ? node.data : text(node.data);
+	case Node.COMMENT_NODE:
+		return '<!--' + node.data + '-->';
+	default:
+		return '';
+	}
+};
+return serialize(document.documentElement, null);
+})()`
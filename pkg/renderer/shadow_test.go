@@ -0,0 +1,52 @@
+package renderer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os/exec"
+	"testing"
+)
+
+// fakeDOM is the part of the DOM the serializer reads, enough to run it in node.
+const fakeDOM = `
+const Node = {ELEMENT_NODE: 1, TEXT_NODE: 3, COMMENT_NODE: 8};
+const text = data => ({nodeType: 3, data});
+const comment = data => ({nodeType: 8, data});
+const el = (tag, attrs, children, extra) => Object.assign({
+	nodeType: 1,
+	localName: tag,
+	attributes: Object.entries(attrs).map(([name, value]) => ({name, value})),
+	childNodes: children,
+}, extra);
+const document = {documentElement: el('html', {lang: 'en'}, [
+	el('head', {}, [
+		el('meta', {charset: 'utf-8'}, []),
+		el('script', {}, [text('if (a < b && c) {}')]),
+	]),
+	el('body', {}, [
+		comment(' app '),
+		el('x-card', {title: 'Tom & "Jerry"'}, [text('light')], {
+			shadowRoot: {mode: 'open', childNodes: [
+				el('style', {}, [text('p > b {}')]),
+				el('p', {}, [text('a < b & c'), el('slot', {}, [])]),
+			]},
+		}),
+		el('template', {id: 't'}, [], {content: {childNodes: [el('br', {}, [])]}}),
+	]),
+])};
+`
+
+func Test_shadowSerializer(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+
+	out, err := exec.Command(node, "-e", fakeDOM+"process.stdout.write("+shadowSerializer+")").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	assert.Equal(t, `<html lang="en"><head><meta charset="utf-8"><script>if (a < b && c) {}</script></head>`+
+		`<body><!-- app --><x-card title="Tom &amp; &quot;Jerry&quot;">`+
+		`<template shadowrootmode="open"><style>p > b {}</style><p>a &lt; b&nbsp;&amp; c<slot></slot></p></template>`+
+		`light</x-card><template id="t"><br></template></body></html>`, string(out))
+}
@@ -0,0 +1,114 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ValidateConfig are the checks a render must pass to be cached.
+type ValidateConfig struct {
+	// MinLength is the minimum length of the HTML in bytes.
+	MinLength int `json:"minLength"`
+	// Selector must match an element of the page, e.g. "#app .product".
+	Selector string `json:"selector"`
+	// Forbidden texts mark error pages, e.g. "Something went wrong".
+	Forbidden []string `json:"forbidden"`
+	// ForbiddenRegexp are like Forbidden, as regular expressions.
+	ForbiddenRegexp []Regexp `json:"forbiddenRegexp"`
+	// Status are the accepted HTTP statuses of the document, any below 400 if empty.
+	Status []int `json:"status"`
+	// Retries is how many times a failing page is rendered again, 1 if not set.
+	Retries *int `json:"retries"`
+}
+
+// ValidationError is returned by Render when the page failed the validation of its host,
+// the page is returned anyway.
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return "error: render not valid, " + e.Reason
+}
+
+// Regexp is a regular expression read from a JSON string, it is compiled when the config
+// is loaded so a bad pattern fails the load.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (r *Regexp) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+	r.Regexp = re
+	return nil
+}
+
+func (r Regexp) MarshalJSON() ([]byte, error) {
+	if r.Regexp == nil {
+		return json.Marshal("")
+	}
+	return json.Marshal(r.String())
+}
+
+func (c *ValidateConfig) retries() int {
+	if c.Retries == nil {
+		return 1
+	}
+	return *c.Retries
+}
+
+// check returns why the page is not valid, "" if it is.
+// status is the HTTP status of the document, 0 if unknown, found whether Selector matched.
+func (c *ValidateConfig) check(html string, status int64, found bool) string {
+	if c == nil {
+		return ""
+	}
+
+	if len(html) < c.MinLength {
+		return fmt.Sprintf("length %d less than %d", len(html), c.MinLength)
+	}
+	if c.Selector != "" && !found {
+		return fmt.Sprintf("selector %q not found", c.Selector)
+	}
+	for _, text := range c.Forbidden {
+		if strings.Contains(html, text) {
+			return fmt.Sprintf("forbidden text %q found", text)
+		}
+	}
+	for _, re := range c.ForbiddenRegexp {
+		if re.Regexp != nil && re.MatchString(html) {
+			return fmt.Sprintf("forbidden regexp %q matched", re.String())
+		}
+	}
+	if status != 0 && !c.statusAllowed(status) {
+		return fmt.Sprintf("status %d", status)
+	}
+	return ""
+}
+
+func (c *ValidateConfig) statusAllowed(status int64) bool {
+	if len(c.Status) == 0 {
+		return status < 400
+	}
+	for _, s := range c.Status {
+		if int64(s) == status {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorScript evaluates to whether selector matches an element of the document.
+func selectorScript(selector string) string {
+	b, _ := json.Marshal(selector)
+	return fmt.Sprintf("document.querySelector(%s) !== null", b)
+}
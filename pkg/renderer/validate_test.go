@@ -0,0 +1,46 @@
+package renderer
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestValidateConfig_check(t *testing.T) {
+	var none *ValidateConfig
+	assert.Empty(t, none.check("", 500, false))
+
+	c := &ValidateConfig{
+		MinLength:       10,
+		Selector:        "#app",
+		Forbidden:       []string{"Something went wrong"},
+		ForbiddenRegexp: []Regexp{{regexp.MustCompile(`(?i)under\s+maintenance`)}},
+	}
+	page := "<html><div id=app>Product</div></html>"
+
+	assert.Empty(t, c.check(page, 200, true))
+	assert.Empty(t, c.check(page, 0, true))
+	assert.Contains(t, c.check("<html>", 200, true), "length")
+	assert.Contains(t, c.check(page, 200, false), "selector")
+	assert.Contains(t, c.check(page+"Something went wrong", 200, true), "forbidden text")
+	assert.Contains(t, c.check(page+"Site Under  Maintenance", 200, true), "forbidden regexp")
+	assert.Contains(t, c.check(page, 503, true), "status")
+
+	c.Status = []int{200, 404}
+	assert.Empty(t, c.check(page, 404, true))
+	assert.NotEmpty(t, c.check(page, 301, true))
+	assert.Equal(t, 1, c.retries())
+}
+
+func TestValidateConfig_UnmarshalJSON(t *testing.T) {
+	var c ValidateConfig
+	assert.NoError(t, json.Unmarshal([]byte(`{"forbiddenRegexp": ["(?i)under\\s+maintenance"]}`), &c))
+	assert.Contains(t, c.check("Site Under  Maintenance", 0, false), "forbidden regexp")
+
+	b, err := json.Marshal(c.ForbiddenRegexp)
+	assert.NoError(t, err)
+	assert.Equal(t, `["(?i)under\\s+maintenance"]`, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"forbiddenRegexp": ["(unclosed"]}`), &c))
+}
@@ -0,0 +1,48 @@
+package seo
+
+import (
+	"strings"
+)
+
+// Robots are the robots directives of a page, from its robots meta tags and X-Robots-Tag headers.
+type Robots struct {
+	NoIndex   bool `json:"noindex,omitempty"`
+	NoFollow  bool `json:"nofollow,omitempty"`
+	NoArchive bool `json:"noarchive,omitempty"`
+	NoSnippet bool `json:"nosnippet,omitempty"`
+	// Directives are all the directives as written, lower cased.
+	Directives []string `json:"directives,omitempty"`
+}
+
+// ParseRobots parses a comma separated list of directives, e.g. "noindex, nofollow".
+// Directives for a named crawler ("googlebot: noindex") are kept in Directives only.
+func ParseRobots(content string) Robots {
+	var r Robots
+	r.Add(content)
+	return r
+}
+
+// Add adds the directives of content.
+func (r *Robots) Add(content string) {
+	// headers sent several times are joined by new lines
+	for _, directive := range strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == '\n' }) {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "" {
+			continue
+		}
+		r.Directives = append(r.Directives, directive)
+
+		switch directive {
+		case "noindex":
+			r.NoIndex = true
+		case "nofollow":
+			r.NoFollow = true
+		case "none":
+			r.NoIndex, r.NoFollow = true, true
+		case "noarchive":
+			r.NoArchive = true
+		case "nosnippet":
+			r.NoSnippet = true
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package seo extracts the metadata search engines read from a rendered page.
+package seo
+
+import (
+	"encoding/json"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strings"
+)
+
+const (
+	maxHeadings    = 100
+	maxHeadingText = 300
+	maxLinks       = 500
+)
+
+// Metadata is what search engines read from a page.
+type Metadata struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Robots      Robots `json:"robots"`
+	// Canonical and the alternate URLs are absolute.
+	Canonical  string            `json:"canonical,omitempty"`
+	Alternates []Alternate       `json:"alternates,omitempty"`
+	OpenGraph  map[string]string `json:"openGraph,omitempty"`
+	Twitter    map[string]string `json:"twitter,omitempty"`
+	Headings   []Heading         `json:"headings,omitempty"`
+	// JSONLD are the valid JSON-LD blocks.
+	JSONLD []json.RawMessage `json:"jsonLd,omitempty"`
+	// Words is the number of words of the visible text of the body.
+	Words int `json:"words"`
+	// Links are the absolute http(s) URLs of the anchors, without fragment and duplicates.
+	Links []string `json:"links,omitempty"`
+}
+
+// Alternate is a hreflang alternate of the page.
+type Alternate struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// Heading is a h1-h6 heading of the page.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// Parse extracts the metadata of the document. pageURL resolves the relative links.
+func Parse(document, pageURL string) (*Metadata, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+	base, _ := url.Parse(pageURL)
+
+	m := &Metadata{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			m.element(n, base)
+			if n.DataAtom == atom.Body {
+				m.Words = words(n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return m, nil
+}
+
+// words counts the words of the visible text of the node. The templates of declarative shadow
+// roots are shown, e.g. the shadow DOM serialized by the renderer.
+func words(n *html.Node) int {
+	switch {
+	case n.Type == html.TextNode:
+		return len(strings.Fields(n.Data))
+	case n.Type == html.ElementNode && hidden[n.DataAtom] && !shadowRoot(n):
+		return 0
+	}
+	count := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		count += words(c)
+	}
+	return count
+}
+
+// shadowRoot reports whether n is the template of a declarative shadow root.
+func shadowRoot(n *html.Node) bool {
+	return n.DataAtom == atom.Template && attr(n, "shadowrootmode") != ""
+}
+
+// crawlers are the meta names holding robots directives, the generic one and the ones of
+// the crawlers rendering is for.
+var crawlers = map[string]bool{"robots": true, "googlebot": true, "bingbot": true}
+
+var hidden = map[atom.Atom]bool{atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true}
+
+func (m *Metadata) element(n *html.Node, base *url.URL) {
+	switch n.DataAtom {
+	case atom.Title:
+		if m.Title == "" {
+			m.Title = text(n)
+		}
+	case atom.Meta:
+		name := strings.ToLower(attr(n, "name"))
+		property := strings.ToLower(attr(n, "property"))
+		content := strings.TrimSpace(attr(n, "content"))
+		switch {
+		case name == "description":
+			m.Description = content
+		case crawlers[name]:
+			m.Robots.Add(content)
+		case strings.HasPrefix(property, "og:"):
+			m.OpenGraph = set(m.OpenGraph, strings.TrimPrefix(property, "og:"), content)
+		case strings.HasPrefix(name, "twitter:"):
+			m.Twitter = set(m.Twitter, strings.TrimPrefix(name, "twitter:"), content)
+		}
+	case atom.Link:
+		rel := strings.Fields(strings.ToLower(attr(n, "rel")))
+		href := resolve(base, attr(n, "href"))
+		for _, r := range rel {
+			switch {
+			case r == "canonical":
+				m.Canonical = href
+			case r == "alternate" && attr(n, "hreflang") != "":
+				m.Alternates = append(m.Alternates, Alternate{Lang: attr(n, "hreflang"), URL: href})
+			}
+		}
+	case atom.A:
+		href := resolve(base, attr(n, "href"))
+		if u, err := url.Parse(href); err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(m.Links) < maxLinks {
+			u.Fragment = ""
+			m.addLink(u.String())
+		}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		if len(m.Headings) < maxHeadings {
+			t := text(n)
+			if len(t) > maxHeadingText {
+				t = t[:maxHeadingText]
+			}
+			m.Headings = append(m.Headings, Heading{Level: int(n.Data[1] - '0'), Text: t})
+		}
+	case atom.Script:
+		if strings.EqualFold(strings.TrimSpace(attr(n, "type")), "application/ld+json") {
+			if n.FirstChild == nil {
+				return
+			}
+			if b := []byte(strings.TrimSpace(n.FirstChild.Data)); json.Valid(b) {
+				m.JSONLD = append(m.JSONLD, b)
+			}
+		}
+	}
+}
+
+func (m *Metadata) addLink(link string) {
+	for _, l := range m.Links {
+		if l == link {
+			return
+		}
+	}
+	m.Links = append(m.Links, link)
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// text returns the text of the node with the white space collapsed.
+func text(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func set(m map[string]string, key, value string) map[string]string {
+	if m == nil {
+		m = map[string]string{}
+	}
+	m[key] = value
+	return m
+}
+
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if base == nil || href == "" {
+		return href
+	}
+	u, err := base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
@@ -0,0 +1,69 @@
+package seo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const document = `<html lang="en"><head>
+<title> Shoes
+ for sale </title>
+<meta name="description" content="All the shoes">
+<meta name="ROBOTS" content="NoIndex, follow">
+<link rel="canonical" href="/shoes">
+<link rel="alternate" hreflang="de" href="https://example.com/de/shoes">
+<meta property="og:title" content="Shoes">
+<meta name="twitter:card" content="summary">
+<script type="application/ld+json">{"@type": "Product",  "name": "Shoe"}</script>
+<script type="application/ld+json">{broken</script>
+</head><body><h1>Shoes <small>new</small></h1><h3>Sizes</h3><a href="/boots#top">Boots</a><a href="boots">Boots</a><a href="mailto:a@example.com">Mail</a><script>var a = 1</script></body></html>`
+
+func TestParse(t *testing.T) {
+	m, err := Parse(document, "https://example.com/shoes?page=1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Shoes for sale", m.Title)
+	assert.Equal(t, "All the shoes", m.Description)
+	assert.True(t, m.Robots.NoIndex)
+	assert.False(t, m.Robots.NoFollow)
+	assert.Equal(t, "https://example.com/shoes", m.Canonical)
+	assert.Equal(t, []Alternate{{Lang: "de", URL: "https://example.com/de/shoes"}}, m.Alternates)
+	assert.Equal(t, map[string]string{"title": "Shoes"}, m.OpenGraph)
+	assert.Equal(t, map[string]string{"card": "summary"}, m.Twitter)
+	assert.Equal(t, []Heading{{Level: 1, Text: "Shoes new"}, {Level: 3, Text: "Sizes"}}, m.Headings)
+	assert.Equal(t, 6, m.Words)
+	assert.Equal(t, []string{"https://example.com/boots"}, m.Links)
+	if assert.Len(t, m.JSONLD, 1) {
+		assert.Equal(t, `{"@type": "Product",  "name": "Shoe"}`, string(m.JSONLD[0]))
+	}
+}
+
+func TestParse_shadowRoot(t *testing.T) {
+	document := `<html><body><my-app><template shadowrootmode="open"><h1>Shadow shoes</h1><p>In the shadow root</p></template></my-app>` +
+		`<template><p>Not shown</p></template></body></html>`
+	m, err := Parse(document, "https://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, m.Words)
+	assert.Equal(t, []Heading{{Level: 1, Text: "Shadow shoes"}}, m.Headings)
+}
+
+func TestParseRobots(t *testing.T) {
+	r := ParseRobots("none")
+	assert.True(t, r.NoIndex)
+	assert.True(t, r.NoFollow)
+
+	r = ParseRobots("googlebot: noindex, noarchive")
+	assert.False(t, r.NoIndex)
+	assert.True(t, r.NoArchive)
+	assert.Equal(t, []string{"googlebot: noindex", "noarchive"}, r.Directives)
+
+	assert.False(t, ParseRobots("").NoIndex)
+
+	m, err := Parse(`<html><head><meta name="Googlebot" content="noindex"></head></html>`, "")
+	assert.NoError(t, err)
+	assert.True(t, m.Robots.NoIndex)
+	m, err = Parse(`<html><head><meta name="bingbot" content="nofollow"><meta name="otherbot" content="noindex"></head></html>`, "")
+	assert.NoError(t, err)
+	assert.True(t, m.Robots.NoFollow)
+	assert.False(t, m.Robots.NoIndex)
+}